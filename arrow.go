@@ -0,0 +1,674 @@
+// Copyright (c) 2016, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Arrow message header types.
+const (
+	arrowSchema          = 1
+	arrowDictionaryBatch = 2
+	arrowRecordBatch     = 3
+)
+
+// Arrow data types.
+const (
+	arrowInt          = 2
+	arrowFloat        = 3
+	arrowBinary       = 4
+	arrowUtf8         = 5
+	arrowBool         = 6
+	arrowDate         = 8
+	arrowTimestamp    = 10
+	arrowLargeBinary  = 19
+	arrowLargeUtf8    = 20
+	arrowMetadataV5   = 4
+	arrowContinuation = 0xFFFFFFFF
+)
+
+// fbTable is a flatbuffers table to be encoded.
+type fbTable []fbField
+
+// fbField is a field of a flatbuffers table. If ref is not nil, the field
+// is an offset to ref (an fbTable, a string, an []fbTable, or fbStructs),
+// otherwise it is a scalar of the given size.
+type fbField struct {
+	id   int
+	size int
+	val  uint64
+	ref  interface{}
+}
+
+// fbStructs is a vector of structs.
+type fbStructs struct {
+	n    int
+	data []byte
+}
+
+// fbEncoder encodes a flatbuffer. Objects are written after the objects
+// that refer to them, so all offsets are positive.
+type fbEncoder struct {
+	b []byte
+}
+
+func (e *fbEncoder) pad(align int) {
+	for len(e.b)%align != 0 {
+		e.b = append(e.b, 0)
+	}
+}
+
+func (e *fbEncoder) u32(v uint32) {
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], v)
+	e.b = append(e.b, buf[:]...)
+}
+
+// offset sets the offset at pos to point to target.
+func (e *fbEncoder) offset(pos, target int) {
+	binary.LittleEndian.PutUint32(e.b[pos:], uint32(target-pos))
+}
+
+// encode writes an object and returns its position.
+func (e *fbEncoder) encode(obj interface{}) int {
+	switch o := obj.(type) {
+	case string:
+		e.pad(4)
+		pos := len(e.b)
+		e.u32(uint32(len(o)))
+		e.b = append(e.b, o...)
+		e.b = append(e.b, 0)
+		return pos
+	case fbStructs:
+		// struct data is aligned to 8 bytes
+		for (len(e.b)+4)%8 != 0 {
+			e.b = append(e.b, 0)
+		}
+		pos := len(e.b)
+		e.u32(uint32(o.n))
+		e.b = append(e.b, o.data...)
+		return pos
+	case []fbTable:
+		e.pad(4)
+		pos := len(e.b)
+		e.u32(uint32(len(o)))
+		slots := len(e.b)
+		e.b = append(e.b, make([]byte, 4*len(o))...)
+		for i, t := range o {
+			e.offset(slots+4*i, e.encode(t))
+		}
+		return pos
+	case fbTable:
+		fields := append(fbTable{}, o...)
+		for i := range fields {
+			if fields[i].ref != nil {
+				fields[i].size = 4
+			}
+		}
+		sort.SliceStable(fields, func(i, j int) bool { return fields[i].size > fields[j].size })
+		maxID := -1
+		off := make([]int, len(fields))
+		size := 4
+		for i, f := range fields {
+			for size%f.size != 0 {
+				size++
+			}
+			off[i] = size
+			size += f.size
+			if f.id > maxID {
+				maxID = f.id
+			}
+		}
+
+		// vtable
+		e.pad(2)
+		vt := len(e.b)
+		vtab := make([]byte, 4+2*(maxID+1))
+		binary.LittleEndian.PutUint16(vtab, uint16(len(vtab)))
+		binary.LittleEndian.PutUint16(vtab[2:], uint16(size))
+		for i, f := range fields {
+			binary.LittleEndian.PutUint16(vtab[4+2*f.id:], uint16(off[i]))
+		}
+		e.b = append(e.b, vtab...)
+
+		e.pad(8)
+		pos := len(e.b)
+		e.b = append(e.b, make([]byte, size)...)
+		binary.LittleEndian.PutUint32(e.b[pos:], uint32(pos-vt))
+		for i, f := range fields {
+			if f.ref != nil {
+				continue
+			}
+			p := e.b[pos+off[i]:]
+			switch f.size {
+			case 1:
+				p[0] = byte(f.val)
+			case 2:
+				binary.LittleEndian.PutUint16(p, uint16(f.val))
+			case 4:
+				binary.LittleEndian.PutUint32(p, uint32(f.val))
+			case 8:
+				binary.LittleEndian.PutUint64(p, f.val)
+			}
+		}
+		for i, f := range fields {
+			if f.ref != nil {
+				e.offset(pos+off[i], e.encode(f.ref))
+			}
+		}
+		return pos
+	}
+	panic(fmt.Sprintf("fbEncoder: unknown object %T", obj))
+}
+
+// encodeFlatbuffer returns a flatbuffer with the given root table.
+func encodeFlatbuffer(root fbTable) []byte {
+	e := &fbEncoder{b: make([]byte, 4)}
+	e.offset(0, e.encode(root))
+	return e.b
+}
+
+// newArrowWriter returns a writer for Arrow IPC streams. All the rows are
+// written in a single record batch.
+func newArrowWriter(out io.Writer, param string) recordWriter {
+	return &columnarWriter{out: out, write: writeArrow}
+}
+
+// writeArrowMessage writes an encapsulated message of an Arrow stream.
+func writeArrowMessage(w io.Writer, header int, h fbTable, body []byte) error {
+	meta := encodeFlatbuffer(fbTable{
+		{id: 0, size: 2, val: arrowMetadataV5},
+		{id: 1, size: 1, val: uint64(header)},
+		{id: 2, ref: h},
+		{id: 3, size: 8, val: uint64(len(body))},
+	})
+	for len(meta)%8 != 0 {
+		meta = append(meta, 0)
+	}
+	var pre [8]byte
+	binary.LittleEndian.PutUint32(pre[:], arrowContinuation)
+	binary.LittleEndian.PutUint32(pre[4:], uint32(len(meta)))
+	if _, err := w.Write(pre[:]); err != nil {
+		return err
+	}
+	if _, err := w.Write(meta); err != nil {
+		return err
+	}
+	_, err := w.Write(body)
+	return err
+}
+
+// writeArrow writes a table as an Arrow IPC stream.
+func writeArrow(out io.Writer, header []string, kinds []int, recs [][]string) error {
+	w := bufio.NewWriter(out)
+	var fields []fbTable
+	for i, h := range header {
+		f := fbTable{
+			{id: 0, ref: h},
+			{id: 1, size: 1, val: 1},
+			{id: 5, ref: []fbTable{}},
+		}
+		switch kinds[i] {
+		case intKind:
+			f = append(f, fbField{id: 2, size: 1, val: arrowInt}, fbField{id: 3, ref: fbTable{
+				{id: 0, size: 4, val: 64},
+				{id: 1, size: 1, val: 1},
+			}})
+		case floatKind:
+			f = append(f, fbField{id: 2, size: 1, val: arrowFloat}, fbField{id: 3, ref: fbTable{
+				{id: 0, size: 2, val: 2}, // double
+			}})
+		default:
+			f = append(f, fbField{id: 2, size: 1, val: arrowUtf8}, fbField{id: 3, ref: fbTable{}})
+		}
+		fields = append(fields, f)
+	}
+	schema := fbTable{
+		{id: 0, size: 2, val: 0},
+		{id: 1, ref: fields},
+	}
+	if err := writeArrowMessage(w, arrowSchema, schema, nil); err != nil {
+		return err
+	}
+
+	if len(recs) > 0 {
+		var body, nodes, bufs []byte
+		var buf [16]byte
+		addBuffer := func(b []byte) {
+			binary.LittleEndian.PutUint64(buf[:], uint64(len(body)))
+			binary.LittleEndian.PutUint64(buf[8:], uint64(len(b)))
+			bufs = append(bufs, buf[:]...)
+			body = append(body, b...)
+			for len(body)%8 != 0 {
+				body = append(body, 0)
+			}
+		}
+		for i := range header {
+			valid := make([]byte, (len(recs)+7)/8)
+			nulls := 0
+			var data, offsets []byte
+			for j, rec := range recs {
+				f := cell(rec, i)
+				if len(f) > 0 {
+					valid[j/8] |= 1 << uint(j%8)
+				} else {
+					nulls++
+				}
+				switch kinds[i] {
+				case intKind:
+					v, _ := strconv.ParseInt(f, 10, 64)
+					binary.LittleEndian.PutUint64(buf[:], uint64(v))
+					data = append(data, buf[:8]...)
+				case floatKind:
+					v, _ := strconv.ParseFloat(f, 64)
+					binary.LittleEndian.PutUint64(buf[:], math.Float64bits(v))
+					data = append(data, buf[:8]...)
+				default:
+					if j == 0 {
+						offsets = append(offsets, 0, 0, 0, 0)
+					}
+					data = append(data, f...)
+					binary.LittleEndian.PutUint32(buf[:], uint32(len(data)))
+					offsets = append(offsets, buf[:4]...)
+				}
+			}
+			binary.LittleEndian.PutUint64(buf[:], uint64(len(recs)))
+			binary.LittleEndian.PutUint64(buf[8:], uint64(nulls))
+			nodes = append(nodes, buf[:]...)
+			addBuffer(valid)
+			if kinds[i] == stringKind {
+				addBuffer(offsets)
+			}
+			addBuffer(data)
+		}
+		batch := fbTable{
+			{id: 0, size: 8, val: uint64(len(recs))},
+			{id: 1, ref: fbStructs{n: len(header), data: nodes}},
+			{id: 2, ref: fbStructs{n: len(bufs) / 16, data: bufs}},
+		}
+		if err := writeArrowMessage(w, arrowRecordBatch, batch, body); err != nil {
+			return err
+		}
+	}
+
+	// end of stream
+	var eos [8]byte
+	binary.LittleEndian.PutUint32(eos[:], arrowContinuation)
+	w.Write(eos[:])
+	return w.Flush()
+}
+
+// fbReader reads a flatbuffer. Reads outside the buffer return zero values
+// and set the error.
+type fbReader struct {
+	b   []byte
+	err error
+}
+
+func (r *fbReader) check(pos, size int) bool {
+	if pos < 0 || pos+size > len(r.b) {
+		r.err = errors.New("invalid flatbuffer")
+		return false
+	}
+	return true
+}
+
+func (r *fbReader) uint(pos, size int) uint64 {
+	if !r.check(pos, size) {
+		return 0
+	}
+	switch size {
+	case 1:
+		return uint64(r.b[pos])
+	case 2:
+		return uint64(binary.LittleEndian.Uint16(r.b[pos:]))
+	case 4:
+		return uint64(binary.LittleEndian.Uint32(r.b[pos:]))
+	}
+	return binary.LittleEndian.Uint64(r.b[pos:])
+}
+
+// fbTableRef is a table in a flatbuffer.
+type fbTableRef struct {
+	r   *fbReader
+	pos int
+}
+
+// root returns the root table.
+func (r *fbReader) root() fbTableRef {
+	return fbTableRef{r, int(r.uint(0, 4))}
+}
+
+// field returns the position of a field, or -1 if the field is not set.
+func (t fbTableRef) field(id int) int {
+	vt := t.pos - int(int32(t.r.uint(t.pos, 4)))
+	vtLen := int(t.r.uint(vt, 2))
+	if 4+2*id+2 > vtLen {
+		return -1
+	}
+	off := int(t.r.uint(vt+4+2*id, 2))
+	if off == 0 {
+		return -1
+	}
+	return t.pos + off
+}
+
+// scalar returns the value of a scalar field.
+func (t fbTableRef) scalar(id, size int) uint64 {
+	p := t.field(id)
+	if p < 0 {
+		return 0
+	}
+	return t.r.uint(p, size)
+}
+
+// ref returns the position of the object referenced by a field.
+func (t fbTableRef) ref(id int) int {
+	p := t.field(id)
+	if p < 0 {
+		return -1
+	}
+	return p + int(t.r.uint(p, 4))
+}
+
+// table returns a table field.
+func (t fbTableRef) table(id int) (fbTableRef, bool) {
+	p := t.ref(id)
+	return fbTableRef{t.r, p}, p >= 0
+}
+
+// vector returns the position of the first element, and the length, of a
+// vector field.
+func (t fbTableRef) vector(id int) (int, int) {
+	p := t.ref(id)
+	if p < 0 {
+		return 0, 0
+	}
+	n := int(t.r.uint(p, 4))
+	if n < 0 || !t.r.check(p+4, n) {
+		return 0, 0
+	}
+	return p + 4, n
+}
+
+// str returns a string field.
+func (t fbTableRef) str(id int) string {
+	p, n := t.vector(id)
+	if !t.r.check(p, n) {
+		return ""
+	}
+	return string(t.r.b[p : p+n])
+}
+
+// arrowColumn is a column of an Arrow stream being read.
+type arrowColumn struct {
+	typ    int
+	width  int  // bit width of integers, and size of floats
+	signed bool // integers are signed
+	unit   int  // unit of dates and timestamps
+	vals   []string
+}
+
+// newArrowReader returns a reader for Arrow IPC streams (and Arrow IPC
+// files). Dictionary encoded and compressed batches are not supported.
+func newArrowReader(in io.Reader, param string) (recordReader, error) {
+	br := bufio.NewReader(in)
+	if m, err := br.Peek(6); err == nil && string(m) == "ARROW1" {
+		// file format: the stream is after the magic number
+		br.Discard(8)
+	}
+	var header []string
+	var cols []*arrowColumn
+	rows := 0
+	var pre [4]byte
+	for {
+		if _, err := io.ReadFull(br, pre[:]); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		l := binary.LittleEndian.Uint32(pre[:])
+		if l == arrowContinuation {
+			if _, err := io.ReadFull(br, pre[:]); err != nil {
+				return nil, err
+			}
+			l = binary.LittleEndian.Uint32(pre[:])
+		}
+		if l == 0 {
+			break
+		}
+		if l > 1<<30 {
+			return nil, errors.New("arrow: invalid message")
+		}
+		meta := make([]byte, l)
+		if _, err := io.ReadFull(br, meta); err != nil {
+			return nil, err
+		}
+		fb := &fbReader{b: meta}
+		msg := fb.root()
+		bodyLen := int64(msg.scalar(3, 8))
+		if bodyLen < 0 || bodyLen > 1<<40 {
+			return nil, errors.New("arrow: invalid message")
+		}
+		body := make([]byte, bodyLen)
+		if _, err := io.ReadFull(br, body); err != nil {
+			return nil, err
+		}
+		h, _ := msg.table(2)
+		switch msg.scalar(1, 1) {
+		case arrowSchema:
+			if header != nil {
+				return nil, errors.New("arrow: duplicated schema")
+			}
+			header = []string{}
+			p, n := h.vector(1)
+			for i := 0; i < n; i++ {
+				q := p + 4*i
+				f := fbTableRef{fb, q + int(fb.uint(q, 4))}
+				header = append(header, f.str(0))
+				if _, ok := f.table(4); ok {
+					return nil, errors.New("arrow: dictionary encoded columns are not supported")
+				}
+				tp, _ := f.table(3)
+				c := &arrowColumn{typ: int(f.scalar(2, 1))}
+				switch c.typ {
+				case arrowInt:
+					c.width = int(tp.scalar(0, 4))
+					c.signed = tp.scalar(1, 1) != 0
+				case arrowFloat:
+					c.width = []int{2, 4, 8}[tp.scalar(0, 2)%3]
+				case arrowDate, arrowTimestamp:
+					c.unit = int(tp.scalar(0, 2))
+				case arrowBinary, arrowUtf8, arrowBool, arrowLargeBinary, arrowLargeUtf8:
+				default:
+					return nil, fmt.Errorf("arrow: column %s: unsupported type %d", f.str(0), c.typ)
+				}
+				if c.typ == arrowFloat && c.width == 2 {
+					return nil, fmt.Errorf("arrow: column %s: half floats are not supported", f.str(0))
+				}
+				cols = append(cols, c)
+			}
+		case arrowRecordBatch:
+			if header == nil {
+				return nil, errors.New("arrow: record batch before schema")
+			}
+			if _, ok := h.table(3); ok {
+				return nil, errors.New("arrow: compressed batches are not supported")
+			}
+			n := int(h.scalar(0, 8))
+			bp, nb := h.vector(2)
+			buffer := func(i int) []byte {
+				if i >= nb {
+					fb.err = errors.New("missing buffer")
+					return nil
+				}
+				off := int64(fb.uint(bp+16*i, 8))
+				ln := int64(fb.uint(bp+16*i+8, 8))
+				if off < 0 || ln < 0 || off+ln > int64(len(body)) {
+					fb.err = errors.New("invalid buffer")
+					return nil
+				}
+				return body[off : off+ln]
+			}
+			b := 0
+			for i, c := range cols {
+				valid := buffer(b)
+				b++
+				data := buffer(b)
+				b++
+				var offsets []byte
+				if c.typ == arrowBinary || c.typ == arrowUtf8 || c.typ == arrowLargeBinary || c.typ == arrowLargeUtf8 {
+					offsets = data
+					data = buffer(b)
+					b++
+				}
+				if fb.err != nil {
+					break
+				}
+				if err := c.read(n, valid, offsets, data); err != nil {
+					return nil, fmt.Errorf("arrow: column %s: %v", header[i], err)
+				}
+			}
+			rows += n
+		case arrowDictionaryBatch:
+			return nil, errors.New("arrow: dictionary batches are not supported")
+		}
+		if fb.err != nil {
+			return nil, fmt.Errorf("arrow: %v", fb.err)
+		}
+	}
+
+	r := &memReader{}
+	if len(header) == 0 {
+		return r, nil
+	}
+	r.recs = append(r.recs, header)
+	for j := 0; j < rows; j++ {
+		rec := make([]string, len(cols))
+		for i, c := range cols {
+			rec[i] = c.vals[j]
+		}
+		r.recs = append(r.recs, rec)
+	}
+	return r, nil
+}
+
+// read reads n values of a column from the buffers of a record batch.
+func (c *arrowColumn) read(n int, valid, offsets, data []byte) error {
+	size := 0
+	switch c.typ {
+	case arrowInt:
+		size = c.width / 8
+	case arrowFloat:
+		size = c.width
+	case arrowDate:
+		size = 4
+		if c.unit == 1 {
+			size = 8
+		}
+	case arrowTimestamp:
+		size = 8
+	case arrowBinary, arrowUtf8:
+		size = 4
+	case arrowLargeBinary, arrowLargeUtf8:
+		size = 8
+	}
+	if c.typ == arrowBool {
+		if len(data)*8 < n {
+			return errors.New("truncated buffer")
+		}
+	} else if offsets != nil {
+		if len(offsets) < (n+1)*size {
+			return errors.New("truncated buffer")
+		}
+	} else if size == 0 || len(data) < n*size {
+		return errors.New("truncated buffer")
+	}
+	if len(valid) > 0 && len(valid)*8 < n {
+		return errors.New("truncated buffer")
+	}
+	for i := 0; i < n; i++ {
+		if len(valid) > 0 && valid[i/8]&(1<<uint(i%8)) == 0 {
+			c.vals = append(c.vals, "")
+			continue
+		}
+		if c.typ == arrowBool {
+			c.vals = append(c.vals, strconv.FormatBool(data[i/8]&(1<<uint(i%8)) != 0))
+			continue
+		}
+		if offsets != nil {
+			var start, end uint64
+			if size == 4 {
+				start = uint64(binary.LittleEndian.Uint32(offsets[4*i:]))
+				end = uint64(binary.LittleEndian.Uint32(offsets[4*i+4:]))
+			} else {
+				start = binary.LittleEndian.Uint64(offsets[8*i:])
+				end = binary.LittleEndian.Uint64(offsets[8*i+8:])
+			}
+			if start > end || end > uint64(len(data)) {
+				return errors.New("invalid offsets")
+			}
+			c.vals = append(c.vals, string(data[start:end]))
+			continue
+		}
+		v := data[i*size : (i+1)*size]
+		var u uint64
+		switch size {
+		case 1:
+			u = uint64(v[0])
+		case 2:
+			u = uint64(binary.LittleEndian.Uint16(v))
+		case 4:
+			u = uint64(binary.LittleEndian.Uint32(v))
+		default:
+			u = binary.LittleEndian.Uint64(v)
+		}
+		c.vals = append(c.vals, c.format(u, size))
+	}
+	return nil
+}
+
+// format formats a fixed size value.
+func (c *arrowColumn) format(u uint64, size int) string {
+	// sign extension
+	s := int64(u)
+	if size < 8 {
+		shift := uint(64 - 8*size)
+		s = int64(u<<shift) >> shift
+	}
+	switch c.typ {
+	case arrowInt:
+		if !c.signed {
+			return strconv.FormatUint(u, 10)
+		}
+		return strconv.FormatInt(s, 10)
+	case arrowFloat:
+		if size == 4 {
+			return formatFloat(float64(math.Float32frombits(uint32(u))))
+		}
+		return formatFloat(math.Float64frombits(u))
+	case arrowDate:
+		if c.unit == 1 {
+			return time.Unix(0, s*int64(time.Millisecond)).UTC().Format("2006-01-02")
+		}
+		return time.Unix(s*86400, 0).UTC().Format("2006-01-02")
+	}
+	// timestamps
+	unit := []time.Duration{time.Second, time.Millisecond, time.Microsecond, time.Nanosecond}[c.unit%4]
+	if unit == time.Second {
+		return time.Unix(s, 0).UTC().Format(time.RFC3339Nano)
+	}
+	return time.Unix(0, s*int64(unit)).UTC().Format(time.RFC3339Nano)
+}
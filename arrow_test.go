@@ -0,0 +1,41 @@
+// Copyright (c) 2016, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestArrow(t *testing.T) {
+	var b bytes.Buffer
+	w := newArrowWriter(&b, "")
+	for _, r := range columnarTable {
+		w.Write(r)
+	}
+	if err := w.Flush(); err != nil {
+		t.Errorf("arrow: unexpected error: %v", err)
+	}
+	if b.Len()%8 != 0 {
+		t.Errorf("arrow: stream length %d is not aligned", b.Len())
+	}
+	r, err := newArrowReader(bytes.NewReader(b.Bytes()), "")
+	if err != nil {
+		t.Fatalf("arrow: unexpected error: %v", err)
+	}
+	testColumnarRead(t, "arrow", r, columnarTable)
+
+	// file format
+	f := append([]byte("ARROW1\x00\x00"), b.Bytes()...)
+	r, err = newArrowReader(bytes.NewReader(f), "")
+	if err != nil {
+		t.Fatalf("arrow: unexpected error: %v", err)
+	}
+	testColumnarRead(t, "arrow", r, columnarTable)
+
+	if _, err := newArrowReader(bytes.NewReader(b.Bytes()[:b.Len()-20]), ""); err == nil {
+		t.Errorf("arrow: expecting error on truncated stream")
+	}
+}
@@ -0,0 +1,344 @@
+// Copyright (c) 2016, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/js-arias/cmdapp"
+)
+
+var catCmd = &cmdapp.Command{
+	Run: catRun,
+	UsageLine: `cat [-f <char>] [--dups <policy>] [--encoding <name>]
+	[--from <format>] [-i|--input <file>] [-n|--no-header]
+	[-o|--output <file>] [--ragged <policy>] [--schema <file>]
+	[--to <format>] [-u|--union] [-x|--intersect] [-s|--source <column>]
+	[<file>...]`,
+	Short: "concatenates tables",
+	Long: `
+Command cat reads one or more tables, and outputs a table with the rows of
+all the tables, in the order given.
+
+By default, all the tables must have the same columns, and the columns of
+the other tables are aligned by name with the columns of the first table.
+With -u or --union, the output table has the columns of all the tables, in
+the order in which they are found, and the columns missing in a table are
+filled with empty fields. With -x or --intersect, the output table only has
+the columns present in all the tables, in the order of the first table.
+
+Each file is read in the format given with --from, or in the format
+detected from its extension. Files without any record are ignored.
+
+Options are:
+
+    -f <char>
+      Sets the field separation character. By default the value is the tab
+      character.
+
+    --dups <policy>
+      Sets how to handle duplicated column names in the header, one of
+      warn (the default), error, or rename. See 'tables help formats'.
+
+    --encoding <name>
+      Sets the character encoding of the input. By default the encoding
+      is detected from the input. See 'tables help formats' for the
+      available encodings.
+
+    --from <format>
+      Sets the format of the input tables. By default the format is
+      detected from the extension of each input file, or it is a
+      delimited text table. See 'tables help formats' for the available
+      formats.
+
+    -i <file>
+    --input <file>
+      If no files are given, read the table from <file> instead of stdin.
+
+    -n
+    --no-header
+      If set, the table will be printed without a header.
+
+    -o <file>
+    --output <file>
+      Write the resulting table to <file> instead of stdout.
+
+    --ragged <policy>
+      Sets how to handle records with a different number of fields than
+      the header, one of error (the default), pad, truncate, or skip. See
+      'tables help formats'.
+
+    --schema <file>
+      Read the types of the columns of the input from a schema file. See
+      'tables help schema'.
+
+    --to <format>
+      Sets the format of the output table. By default the format is
+      detected from the extension of the output file, or it is a delimited
+      text table. See 'tables help formats' for the available formats.
+
+    -u
+    --union
+      If set, the output table has the columns of all the tables.
+
+    -x
+    --intersect
+      If set, the output table has only the columns found in all the
+      tables.
+
+    -s <column>
+    --source <column>
+      If set, adds a column with the given name, with the name of the file
+      of each row ("-" for the standard input).
+
+    <file>
+      One or more files with the tables to concatenate. If no file is
+      given, the table is read from the standard input.
+	`,
+}
+
+var catUnion bool     // use the union of the columns, -u|--union
+var catIntersect bool // use the intersection of the columns, -x|--intersect
+var catSource string  // set source column, -s|--source
+
+func init() {
+	initCommonFlags(catCmd)
+	catCmd.Flag.BoolVar(&catUnion, "union", false, "")
+	catCmd.Flag.BoolVar(&catUnion, "u", false, "")
+	catCmd.Flag.BoolVar(&catIntersect, "intersect", false, "")
+	catCmd.Flag.BoolVar(&catIntersect, "x", false, "")
+	catCmd.Flag.StringVar(&catSource, "source", "", "")
+	catCmd.Flag.StringVar(&catSource, "s", "", "")
+}
+
+func catRun(c *cmdapp.Command, args []string) error {
+	if catUnion && catIntersect {
+		return errors.New("options --union and --intersect can not be used together")
+	}
+	files := args
+	if len(files) == 0 {
+		files = []string{input}
+	}
+
+	// read the headers, regular files are opened again to read the rows,
+	// so only one of them is open at a time, other inputs (e.g. pipes) can
+	// be read only once, so they are kept open
+	var names []string
+	var headers [][]string
+	open := make([]*inTable, len(files))
+	defer func() {
+		for _, t := range open {
+			if t != nil {
+				t.Close()
+			}
+		}
+	}()
+	for i, f := range files {
+		t, err := openTable(f)
+		if err != nil {
+			return err
+		}
+		open[i] = t
+		name := f
+		if len(name) == 0 {
+			name = "-"
+		}
+		names = append(names, name)
+		h, err := t.Read()
+		if err != nil && err != io.EOF {
+			return err
+		}
+		headers = append(headers, h)
+		if isRegular(f) {
+			t.Close()
+			open[i] = nil
+		}
+	}
+	cols, err := catHeader(headers, names)
+	if err != nil {
+		return err
+	}
+	if len(cols) == 0 {
+		return nil
+	}
+	if len(catSource) > 0 {
+		for _, c := range cols {
+			if c == catSource {
+				return fmt.Errorf("source column %q already in the tables", catSource)
+			}
+		}
+		cols = append(cols, catSource)
+	}
+
+	w, err := openOutput()
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	if err := w.Write(cols); err != nil {
+		return err
+	}
+	for i, f := range files {
+		if len(headers[i]) == 0 {
+			continue
+		}
+		t := open[i]
+		if t == nil {
+			if t, err = openTable(f); err != nil {
+				return err
+			}
+			open[i] = t
+			if err := t.skipHeader(headers[i]); err != nil {
+				return err
+			}
+		}
+		if err := catRows(w, t, cols, headers[i], names[i]); err != nil {
+			return err
+		}
+		t.Close()
+		open[i] = nil
+	}
+	return w.Close()
+}
+
+// catRows writes the rows of a table, with the columns of the
+// concatenated table. Name is the name of the table.
+func catRows(w rowWriter, t *inTable, cols, header []string, name string) error {
+	head := catIndex(cols, header)
+	for {
+		rec, err := t.Read()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		row := make([]string, len(cols))
+		for j, h := range head {
+			if h >= 0 {
+				row[j] = cell(rec, h)
+			}
+		}
+		if len(catSource) > 0 {
+			row[len(row)-1] = name
+		}
+		if err := w.Write(row); err != nil {
+			return err
+		}
+	}
+}
+
+// catHeader returns the columns of the concatenated table, from the
+// headers of the tables (empty headers are ignored). Names are the names of
+// the tables. Duplicated column names are matched by their order, i.e. the
+// second column with a name is matched with the second column with that
+// name in the other tables.
+func catHeader(headers [][]string, names []string) ([]string, error) {
+	var cols []string
+	first := -1
+	for i, h := range headers {
+		if len(h) == 0 {
+			continue
+		}
+		if first < 0 {
+			first = i
+			cols = append(cols, h...)
+			continue
+		}
+		switch {
+		case catUnion:
+			seen := make(map[string]int)
+			for _, c := range h {
+				seen[c]++
+				if countColumn(cols, c) < seen[c] {
+					cols = append(cols, c)
+				}
+			}
+		case catIntersect:
+			var in []string
+			seen := make(map[string]int)
+			for _, c := range cols {
+				seen[c]++
+				if countColumn(h, c) >= seen[c] {
+					in = append(in, c)
+				}
+			}
+			if len(in) == 0 {
+				return nil, fmt.Errorf("%s: no columns in common with the previous tables", names[i])
+			}
+			cols = in
+		default:
+			for _, c := range h {
+				if countColumn(h, c) > countColumn(cols, c) {
+					return nil, fmt.Errorf("%s: column %q not in %s", names[i], c, names[first])
+				}
+			}
+			for _, c := range cols {
+				if countColumn(cols, c) > countColumn(h, c) {
+					return nil, fmt.Errorf("%s: column %q not found", names[i], c)
+				}
+			}
+		}
+	}
+	return cols, nil
+}
+
+// countColumn returns the number of columns with a name in a header.
+func countColumn(header []string, col string) int {
+	n := 0
+	for _, h := range header {
+		if h == col {
+			n++
+		}
+	}
+	return n
+}
+
+// isRegular returns true if a file name is a regular file, that can be
+// opened again.
+func isRegular(name string) bool {
+	if len(name) == 0 {
+		return false
+	}
+	fi, err := os.Stat(name)
+	return err == nil && fi.Mode().IsRegular()
+}
+
+// hasColumn returns true if a column is in a header.
+func hasColumn(header []string, col string) bool {
+	for _, h := range header {
+		if h == col {
+			return true
+		}
+	}
+	return false
+}
+
+// catIndex returns the index of each column in a header, or -1 if the
+// column is not in the header. Duplicated column names are matched by
+// their order.
+func catIndex(cols, header []string) []int {
+	head := make([]int, len(cols))
+	seen := make(map[string]int)
+	for i, c := range cols {
+		head[i] = -1
+		n := seen[c]
+		seen[c]++
+		for j, h := range header {
+			if c != h {
+				continue
+			}
+			if n == 0 {
+				head[i] = j
+				break
+			}
+			n--
+		}
+	}
+	return head
+}
@@ -0,0 +1,161 @@
+// Copyright (c) 2016, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCatHeader(t *testing.T) {
+	headers := [][]string{
+		[]string{"Item", "Cost", "Amount"},
+		nil,
+		[]string{"Amount", "Item", "Value"},
+	}
+	names := []string{"a", "b", "c"}
+	tests := []struct {
+		union, intersect bool
+		cols             []string
+	}{
+		{true, false, []string{"Item", "Cost", "Amount", "Value"}},
+		{false, true, []string{"Item", "Amount"}},
+	}
+	defer func() { catUnion, catIntersect = false, false }()
+	for _, ts := range tests {
+		catUnion, catIntersect = ts.union, ts.intersect
+		cols, err := catHeader(headers, names)
+		if err != nil {
+			t.Errorf("Cat: unexpected error: %v", err)
+		}
+		if len(cols) != len(ts.cols) {
+			t.Errorf("Cat: expecting %d columns, found %d", len(ts.cols), len(cols))
+			continue
+		}
+		for i, c := range ts.cols {
+			if cols[i] != c {
+				t.Errorf("Cat: expecting column %q, found %q", c, cols[i])
+			}
+		}
+	}
+
+	catUnion, catIntersect = false, true
+	if _, err := catHeader([][]string{headers[0], []string{"Value"}}, names); err == nil {
+		t.Errorf("Cat: expecting error on tables without common columns")
+	}
+
+	catUnion, catIntersect = false, false
+	if _, err := catHeader(headers, names); err == nil {
+		t.Errorf("Cat: expecting error on different headers")
+	}
+	cols, err := catHeader([][]string{headers[0], []string{"Amount", "Cost", "Item"}}, names)
+	if err != nil {
+		t.Errorf("Cat: unexpected error: %v", err)
+	}
+	if len(cols) != 3 || cols[0] != "Item" {
+		t.Errorf("Cat: expecting columns of the first table, found %v", cols)
+	}
+
+	head := catIndex([]string{"Item", "Cost", "Value"}, headers[2])
+	x := []int{1, -1, 2}
+	for i, v := range x {
+		if head[i] != v {
+			t.Errorf("Cat: expecting index %d, found %d", v, head[i])
+		}
+	}
+}
+
+func TestCatDuplicated(t *testing.T) {
+	headers := [][]string{
+		[]string{"A", "V", "V"},
+		[]string{"V", "A", "V", "W"},
+	}
+	names := []string{"a", "b"}
+	tests := []struct {
+		union, intersect bool
+		cols             string
+		index            []int // index of the columns in the second table
+	}{
+		{false, false, "", nil},
+		{true, false, "A V V W", []int{1, 0, 2, 3}},
+		{false, true, "A V V", []int{1, 0, 2}},
+	}
+	defer func() { catUnion, catIntersect = false, false }()
+	for _, ts := range tests {
+		catUnion, catIntersect = ts.union, ts.intersect
+		cols, err := catHeader(headers, names)
+		if ts.index == nil {
+			if err == nil {
+				t.Errorf("Cat: duplicated: expecting error on different headers")
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Cat: duplicated: unexpected error: %v", err)
+			continue
+		}
+		if s := strings.Join(cols, " "); s != ts.cols {
+			t.Errorf("Cat: duplicated: expecting %q, found %q", ts.cols, s)
+		}
+		head := catIndex(cols, headers[1])
+		if fmt.Sprint(head) != fmt.Sprint(ts.index) {
+			t.Errorf("Cat: duplicated: expecting index %v, found %v", ts.index, head)
+		}
+	}
+
+	catUnion, catIntersect = false, false
+	head := catIndex(headers[0], headers[0])
+	if fmt.Sprint(head) != "[0 1 2]" {
+		t.Errorf("Cat: duplicated: expecting index [0 1 2], found %v", head)
+	}
+	if _, err := catHeader([][]string{headers[0], []string{"V", "A", "V"}}, names); err != nil {
+		t.Errorf("Cat: duplicated: unexpected error: %v", err)
+	}
+}
+
+func TestCatPipe(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tables")
+	if err != nil {
+		t.Fatalf("Cat: unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	a := filepath.Join(dir, "a.tsv")
+	ioutil.WriteFile(a, []byte("Item\tCost\n1\t10\n"), 0644)
+	b := filepath.Join(dir, "b.tsv")
+	ioutil.WriteFile(b, []byte("Cost\tItem\n30\t3\n"), 0644)
+
+	// the standard input is a pipe, that can not be read again
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Cat: unexpected error: %v", err)
+	}
+	go func() {
+		pw.Write([]byte("Item\tCost\n2\t20\n"))
+		pw.Close()
+	}()
+	stdin := os.Stdin
+	os.Stdin = pr
+	defer func() {
+		os.Stdin = stdin
+		pr.Close()
+		output = ""
+	}()
+	output = filepath.Join(dir, "out.tsv")
+	if err := catRun(catCmd, []string{a, "", b}); err != nil {
+		t.Fatalf("Cat: unexpected error: %v", err)
+	}
+	data, err := ioutil.ReadFile(output)
+	if err != nil {
+		t.Fatalf("Cat: unexpected error: %v", err)
+	}
+	x := "Item\tCost\r\n1\t10\r\n2\t20\r\n3\t30\r\n"
+	if string(data) != x {
+		t.Errorf("Cat: expecting %q, found %q", x, data)
+	}
+}
@@ -5,23 +5,62 @@
 package main
 
 import (
-	"encoding/csv"
+	"fmt"
 	"io"
-	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
 
 	"github.com/js-arias/cmdapp"
 )
 
 var colsCmd = &cmdapp.Command{
 	Run: colsRun,
-	UsageLine: `cols [-f <char>] [-i|--input <file>] [-n|--no-header]
-	[-o|--output <file>] [-v|--invert] <column>...`,
+	UsageLine: `cols [-f <char>] [--dups <policy>] [--encoding <name>]
+	[--from <format>] [-i|--input <file>] [-n|--no-header]
+	[-o|--output <file>] [--ragged <policy>] [--schema <file>]
+	[--to <format>] [-v|--invert] <column>...`,
 	Short: "selects columns by name",
 	Long: `
 Command cols selects columns by name and outputs a table with that columns.
 If a column name does not match any of the columns in the table, cols creates
 a new empty column by that name in the indicated location.
 
+Columns can also be selected with:
+
+    #<number>
+      The column at the given position, starting at 1 (e.g. #3). Negative
+      positions count from the last column (e.g. #-1 is the last column).
+
+    #<number>-#<number>
+      The columns between two positions, including both (e.g. #3-#5).
+
+    <column>..<column>
+      The columns between two columns, including both (e.g. Amount..Value).
+
+    /<regexp>/
+      The columns with a name that matches a regular expression (e.g.
+      /^temp_/).
+
+    <pattern>
+      The columns with a name that matches a shell pattern, with *, ?, or
+      [...] (e.g. temp_*).
+
+    :<type>
+      The columns of a type, one of numeric (integer or float), integer,
+      float, boolean, date, or string. The types are taken from the schema
+      (see 'tables help schema'), or from the format, or inferred from the
+      first 1000 rows of the table.
+
+If a column name matches one of these forms, the column is selected by its
+name. The columns selected by a range are in the order of the range, and
+the other columns in the order of the table. If a form does not select any
+column (e.g. a pattern without matches, or a range with an unknown
+column), it is taken as the name of a new empty column, as any other
+column name not found in the table. An invalid pattern, or an unknown
+type, is an error.
+
 If no columns are indicated all the columns in the table will be selected.
 
 This command can be used to select, sort, or delete columns.
@@ -32,6 +71,20 @@ Options are:
       Sets the field separation character. By default the value is the tab
       character.
 
+    --dups <policy>
+      Sets how to handle duplicated column names in the header, one of
+      warn (the default), error, or rename. See 'tables help formats'.
+
+    --encoding <name>
+      Sets the character encoding of the input. By default the encoding
+      is detected from the input. See 'tables help formats' for the
+      available encodings.
+
+    --from <format>
+      Sets the format of the input table. By default the format is
+      detected from the extension of the input file, or it is a delimited
+      text table. See 'tables help formats' for the available formats.
+
     -i <file>
     --input <file>
       Read the table from <file> instead of stdin.
@@ -44,13 +97,27 @@ Options are:
     --output <file>
       Write the resulting table to <file> instead of stdout. 
 
+    --ragged <policy>
+      Sets how to handle records with a different number of fields than
+      the header, one of error (the default), pad, truncate, or skip. See
+      'tables help formats'.
+
+    --schema <file>
+      Read the types of the columns of the input from a schema file. See
+      'tables help schema'.
+
+    --to <format>
+      Sets the format of the output table. By default the format is
+      detected from the extension of the output file, or it is a delimited
+      text table. See 'tables help formats' for the available formats.
+
     -v
     --invert
       Inverts the program behavior, i.e. output only the columns NOT included
       in the arguments.
 
     <column>
-      One or more column names.
+      One or more column names, or column selections.
 	`,
 }
 
@@ -59,58 +126,33 @@ func init() {
 }
 
 func colsRun(c *cmdapp.Command, args []string) error {
-	in := os.Stdin
-	if len(input) > 0 {
-		var err error
-		in, err = os.Open(input)
-		if err != nil {
-			return err
-		}
-		defer in.Close()
-	}
-	out := os.Stdout
-	if len(output) > 0 {
-		var err error
-		out, err = os.Create(output)
-		if err != nil {
-			return err
-		}
-		defer out.Close()
+	r, err := openInput()
+	if err != nil {
+		return err
 	}
-	if len(delim) == 0 {
-		delim = "\t"
+	defer r.Close()
+	w, err := openOutput()
+	if err != nil {
+		return err
 	}
-	r1 := []rune(delim)[0]
-	r := csv.NewReader(in)
-	r.Comma = r1
-	w := csv.NewWriter(out)
-	w.Comma = r1
-	w.UseCRLF = true
-	defer w.Flush()
+	defer w.Close()
 	var head []int
 	var cols []string
 	if invert {
-		var err error
 		cols, head, err = deleteColumns(r, args)
-		if err != nil {
-			return err
-		}
 	} else {
-		var err error
 		cols, head, err = selectColumns(r, args)
-		if err != nil {
-			return err
-		}
+	}
+	if err != nil {
+		return err
 	}
 
 	if len(head) == 0 {
 		return nil
 	}
-	if !noHead {
-		err := w.Write(cols)
-		if err != nil {
-			return err
-		}
+	err = w.Write(cols)
+	if err != nil {
+		return err
 	}
 
 	for {
@@ -126,11 +168,11 @@ func colsRun(c *cmdapp.Command, args []string) error {
 			return err
 		}
 	}
-	return nil
+	return w.Close()
 }
 
 // colsFn return a row with the columns indicated by the head slice.
-func colsFn(r *csv.Reader, head []int) (row []string, err error) {
+func colsFn(r recordReader, head []int) (row []string, err error) {
 	nr, err := r.Read()
 	if err != nil {
 		return nil, err
@@ -140,7 +182,7 @@ func colsFn(r *csv.Reader, head []int) (row []string, err error) {
 		if h == -1 {
 			continue
 		}
-		row[i] = nr[h]
+		row[i] = cell(nr, h)
 	}
 	return row, nil
 }
@@ -149,7 +191,7 @@ func colsFn(r *csv.Reader, head []int) (row []string, err error) {
 // with the column names of the new table, and an int slice with the column
 // order (-1 if the column is new) on the original table. If no columns are
 // indicated, it will return all the columns in the original table.
-func selectColumns(r *csv.Reader, args []string) (cols []string, head []int, err error) {
+func selectColumns(r recordReader, args []string) (cols []string, head []int, err error) {
 	header, err := r.Read()
 	if err != nil {
 		return nil, nil, err
@@ -165,16 +207,19 @@ func selectColumns(r *csv.Reader, args []string) (cols []string, head []int, err
 	}
 
 	// lookup for columns
-	head = make([]int, len(args))
-	cols = make([]string, len(args))
-	copy(cols, args)
-	for i, c := range args {
-		head[i] = -1
-		for j, h := range header {
-			if c == h {
-				head[i] = j
-				break
+	sel := &columnSelector{r: r, header: header}
+	for _, c := range args {
+		idx, err := sel.columns(c)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, j := range idx {
+			head = append(head, j)
+			if j == -1 {
+				cols = append(cols, c)
+				continue
 			}
+			cols = append(cols, header[j])
 		}
 	}
 	return
@@ -182,7 +227,7 @@ func selectColumns(r *csv.Reader, args []string) (cols []string, head []int, err
 
 // deleteColumns returns a slice with columns names of the new table, and an
 // int slice with the number of the retained columns in the original table.
-func deleteColumns(r *csv.Reader, args []string) (cols []string, head []int, err error) {
+func deleteColumns(r recordReader, args []string) (cols []string, head []int, err error) {
 	header, err := r.Read()
 	if err != nil {
 		return nil, nil, err
@@ -194,15 +239,19 @@ func deleteColumns(r *csv.Reader, args []string) (cols []string, head []int, err
 	}
 
 	// removes the columns found
-	for i, h := range header {
-		toDel := false
-		for _, c := range args {
-			if c == h {
-				toDel = true
-				break
-			}
+	sel := &columnSelector{r: r, header: header}
+	toDel := make(map[int]bool)
+	for _, c := range args {
+		idx, err := sel.columns(c)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, j := range idx {
+			toDel[j] = true
 		}
-		if toDel {
+	}
+	for i, h := range header {
+		if toDel[i] {
 			continue
 		}
 		head = append(head, i)
@@ -210,3 +259,238 @@ func deleteColumns(r *csv.Reader, args []string) (cols []string, head []int, err
 	}
 	return
 }
+
+// findColumn returns the index of a column in a header, or -1 if the
+// column is not in the header. A column can be given by its name, or by
+// its position, as #<number>, starting at 1, or if negative, counting from
+// the last column (#-1). It returns an error if the name is found more than
+// once, or if the position is outside the header.
+func findColumn(header []string, col string) (int, error) {
+	idx := -1
+	for i, h := range header {
+		if h != col {
+			continue
+		}
+		if idx >= 0 {
+			return -1, fmt.Errorf("ambiguous column %q: found at #%d and #%d", col, idx+1, i+1)
+		}
+		idx = i
+	}
+	if idx >= 0 || !strings.HasPrefix(col, "#") {
+		return idx, nil
+	}
+	n, err := strconv.Atoi(col[1:])
+	if err != nil {
+		return -1, nil
+	}
+	if n < 0 {
+		n += len(header) + 1
+	}
+	if n < 1 || n > len(header) {
+		return -1, fmt.Errorf("column %s: the table has %d columns", col, len(header))
+	}
+	return n - 1, nil
+}
+
+// peekReader is a table reader that can return the next records, without
+// consuming them.
+type peekReader interface {
+	recordReader
+
+	// Peek returns the next n records, or less if the table ends before.
+	Peek(n int) ([][]string, error)
+}
+
+// typeRows is the number of rows used to infer the type of the columns.
+const typeRows = 1000
+
+// columnSelector resolves the columns of a header given as arguments.
+type columnSelector struct {
+	r      recordReader
+	header []string
+	kinds  []string // types of the columns, set when required
+}
+
+// columns returns the index of the columns indicated by an argument, in
+// the order of the header. If the argument is a column name not found in
+// the header, or a form that does not select any column, it returns -1 as
+// the index.
+func (s *columnSelector) columns(arg string) ([]int, error) {
+	j, err := findColumn(s.header, arg)
+	if err != nil {
+		return nil, err
+	}
+	if j >= 0 {
+		return []int{j}, nil
+	}
+	var idx []int
+	switch {
+	case strings.HasPrefix(arg, "#"):
+		i := strings.Index(arg, "-#")
+		if i < 0 {
+			break
+		}
+		from, err := findColumn(s.header, arg[:i])
+		if err != nil {
+			return nil, err
+		}
+		to, err := findColumn(s.header, arg[i+1:])
+		if err != nil {
+			return nil, err
+		}
+		if from >= 0 && to >= 0 {
+			idx = columnRange(from, to)
+		}
+	case len(arg) > 2 && strings.HasPrefix(arg, "/") && strings.HasSuffix(arg, "/"):
+		re, err := regexp.Compile(arg[1 : len(arg)-1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid column pattern %q: %v", arg, err)
+		}
+		idx = s.match(re.MatchString)
+	case strings.HasPrefix(arg, ":"):
+		if idx, err = s.typed(arg[1:]); err != nil {
+			return nil, err
+		}
+	case strings.Contains(arg, ".."):
+		i := strings.Index(arg, "..")
+		from, err := findColumn(s.header, arg[:i])
+		if err != nil {
+			return nil, err
+		}
+		to, err := findColumn(s.header, arg[i+2:])
+		if err != nil {
+			return nil, err
+		}
+		if from >= 0 && to >= 0 {
+			idx = columnRange(from, to)
+		}
+	case strings.ContainsAny(arg, "*?["):
+		if _, err := path.Match(arg, ""); err != nil {
+			return nil, fmt.Errorf("invalid column pattern %q: %v", arg, err)
+		}
+		idx = s.match(func(h string) bool {
+			ok, _ := path.Match(arg, h)
+			return ok
+		})
+	}
+	if len(idx) > 0 {
+		return idx, nil
+	}
+	// as with any other unknown name
+	return []int{-1}, nil
+}
+
+// splitColumns splits a comma separated list of columns. The commas of a
+// regular expression (e.g. /x{1,2}/) do not split the list.
+func splitColumns(s string) []string {
+	var cols []string
+	start := 0
+	inRE := false
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '/' && i == start:
+			inRE = true
+		case inRE && s[i] == '\\':
+			i++
+		case inRE && s[i] == '/':
+			inRE = false
+		case !inRE && s[i] == ',':
+			cols = append(cols, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(cols, s[start:])
+}
+
+// columnRange returns the columns between from and to, including both.
+// If to is before from, the columns are in reverse order.
+func columnRange(from, to int) []int {
+	var idx []int
+	if from <= to {
+		for i := from; i <= to; i++ {
+			idx = append(idx, i)
+		}
+		return idx
+	}
+	for i := from; i >= to; i-- {
+		idx = append(idx, i)
+	}
+	return idx
+}
+
+// match returns the columns with a name accepted by fn.
+func (s *columnSelector) match(fn func(string) bool) []int {
+	idx := []int{}
+	for i, h := range s.header {
+		if fn(h) {
+			idx = append(idx, i)
+		}
+	}
+	return idx
+}
+
+// typed returns the columns of a type.
+func (s *columnSelector) typed(tp string) ([]int, error) {
+	switch tp {
+	case "numeric", integerSchema, floatSchema, booleanSchema, dateSchema, stringSchema:
+	default:
+		return nil, fmt.Errorf("unknown column type %q", tp)
+	}
+	if s.kinds == nil {
+		if err := s.setKinds(); err != nil {
+			return nil, err
+		}
+	}
+	idx := []int{}
+	for i, k := range s.kinds {
+		if k == tp || (tp == "numeric" && (k == integerSchema || k == floatSchema)) {
+			idx = append(idx, i)
+		}
+	}
+	return idx, nil
+}
+
+// setKinds sets the types of the columns, using the schema of the table,
+// or the types declared by the format, or inferring them from the first
+// rows of the table.
+func (s *columnSelector) setKinds() error {
+	st := make([]*schemaStats, len(s.header))
+	for i := range st {
+		st[i] = newSchemaStats()
+	}
+	if pr, ok := s.r.(peekReader); ok {
+		rows, err := pr.Peek(typeRows)
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			for i := range st {
+				st[i].add(cell(row, i))
+			}
+		}
+	}
+	types := columnTypes(s.r)
+	var sc *tableSchema
+	if t, ok := s.r.(*inTable); ok {
+		sc = t.schema
+	}
+	s.kinds = make([]string, len(s.header))
+	for i, h := range s.header {
+		s.kinds[i] = st[i].kind()
+		if i < len(types) {
+			switch {
+			case types[i] == stringType:
+				s.kinds[i] = stringSchema
+			case types[i] == numberType && s.kinds[i] != integerSchema:
+				s.kinds[i] = floatSchema
+			}
+		}
+		if sc == nil {
+			continue
+		}
+		if c, ok := sc.cols[h]; ok && len(c.typ) > 0 {
+			s.kinds[i] = c.typ
+		}
+	}
+	return nil
+}
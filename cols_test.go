@@ -7,6 +7,7 @@ package main
 import (
 	"encoding/csv"
 	"io"
+	"strconv"
 	"strings"
 	"testing"
 )
@@ -57,7 +58,7 @@ func TestColsSelect(t *testing.T) {
 			t.Errorf("Cols: unexpected error: %v", err)
 		}
 		if len(row) != len(head) {
-			t.Errorf("Cols: expecting vector of %d elements, found %d (row %d)", len(head), len(row))
+			t.Errorf("Cols: expecting vector of %d elements, found %d (row %d)", len(head), len(row), i)
 		}
 		if i < len(rs) {
 			for j, v := range rs[i] {
@@ -103,7 +104,7 @@ func TestAddCols(t *testing.T) {
 			t.Errorf("Cols: unexpected error: %v", err)
 		}
 		if len(row) != len(head) {
-			t.Errorf("Cols: expecting vector of %d elements, found %d (row %d)", len(head), len(row))
+			t.Errorf("Cols: expecting vector of %d elements, found %d (row %d)", len(head), len(row), i)
 		}
 		if i < len(rs) {
 			for j, v := range rs[i] {
@@ -154,7 +155,7 @@ func TestDelCols(t *testing.T) {
 			t.Errorf("Cols: unexpected error: %v", err)
 		}
 		if len(row) != len(head) {
-			t.Errorf("Cols: expecting vector of %d elements, found %d (row %d)", len(head), len(row))
+			t.Errorf("Cols: expecting vector of %d elements, found %d (row %d)", len(head), len(row), i)
 		}
 		if i < len(rs) {
 			for j, v := range rs[i] {
@@ -165,3 +166,124 @@ func TestDelCols(t *testing.T) {
 		}
 	}
 }
+
+func TestFindColumn(t *testing.T) {
+	header := []string{"Item", "Value", "#2", "Value"}
+	tests := []struct {
+		col   string
+		idx   int
+		fails bool
+	}{
+		{"Item", 0, false},
+		{"#1", 0, false},
+		{"#4", 3, false},
+		{"#2", 2, false},
+		{"#-1", 3, false},
+		{"#-4", 0, false},
+		{"Cost", -1, false},
+		{"#x", -1, false},
+		{"Value", -1, true},
+		{"#0", -1, true},
+		{"#5", -1, true},
+		{"#-5", -1, true},
+	}
+	for _, ts := range tests {
+		idx, err := findColumn(header, ts.col)
+		if (err != nil) != ts.fails {
+			t.Errorf("Find column: %q: unexpected error value: %v", ts.col, err)
+		}
+		if idx != ts.idx {
+			t.Errorf("Find column: %q: expecting %d, found %d", ts.col, ts.idx, idx)
+		}
+	}
+}
+
+func TestColumnSelector(t *testing.T) {
+	tests := []struct {
+		args   []string
+		invert bool
+		cols   string
+	}{
+		{[]string{"#-1", "#1"}, false, "Description Item"},
+		{[]string{"#2-#4"}, false, "Amount Cost Value"},
+		{[]string{"#-1-#-2"}, false, "Description Value"},
+		{[]string{"Amount..Value", "Total"}, false, "Amount Cost Value Total"},
+		{[]string{"Value..Cost"}, false, "Value Cost"},
+		{[]string{"/^[A-C]/"}, false, "Amount Cost"},
+		{[]string{"*e*"}, false, "Item Value Description"},
+		{[]string{":numeric"}, false, "Item Amount Cost Value"},
+		{[]string{":string"}, false, "Description"},
+		{[]string{":date", "Item"}, false, ":date Item"},
+		{[]string{"#2-#4", ":string"}, true, "Item"},
+		{[]string{"/e$/", "Total"}, true, "Item Amount Cost Description"},
+	}
+	for _, ts := range tests {
+		tr, err := newTextReader(strings.NewReader(colsBlob), "")
+		if err != nil {
+			t.Fatalf("Cols: unexpected error: %v", err)
+		}
+		r := &inTable{r: tr, name: "data"}
+		var cols []string
+		var head []int
+		if ts.invert {
+			cols, head, err = deleteColumns(r, ts.args)
+		} else {
+			cols, head, err = selectColumns(r, ts.args)
+		}
+		if err != nil {
+			t.Errorf("Cols: %v: unexpected error: %v", ts.args, err)
+			continue
+		}
+		if s := strings.Join(cols, " "); s != ts.cols {
+			t.Errorf("Cols: %v: expecting %q, found %q", ts.args, ts.cols, s)
+		}
+
+		// rows used to infer the types are not lost
+		n := 0
+		for {
+			if _, err := colsFn(r, head); err != nil {
+				break
+			}
+			n++
+		}
+		if n != 7 {
+			t.Errorf("Cols: %v: expecting 7 rows, found %d", ts.args, n)
+		}
+	}
+
+	// forms without columns are new empty columns
+	for _, a := range []string{"Item..Total", "/^x/", "x*"} {
+		tr, _ := newTextReader(strings.NewReader(colsBlob), "")
+		cols, head, err := selectColumns(&inTable{r: tr, name: "data"}, []string{a})
+		if err != nil {
+			t.Errorf("Cols: %q: unexpected error: %v", a, err)
+			continue
+		}
+		if len(cols) != 1 || cols[0] != a || head[0] != -1 {
+			t.Errorf("Cols: %q: expecting a new column, found %v %v", a, cols, head)
+		}
+	}
+
+	for _, a := range []string{"/(/", ":number", ":intger", "[a"} {
+		tr, _ := newTextReader(strings.NewReader(colsBlob), "")
+		_, _, err := selectColumns(&inTable{r: tr, name: "data"}, []string{a})
+		if err == nil || !strings.Contains(err.Error(), strconv.Quote(strings.TrimPrefix(a, ":"))) {
+			t.Errorf("Cols: %q: expecting error, found %v", a, err)
+		}
+	}
+}
+
+func TestSplitColumns(t *testing.T) {
+	tests := map[string]string{
+		"a,b..c":           "a|b..c",
+		"/x{1,2}/,a":       "/x{1,2}/|a",
+		`/a\/b,c/,d,/e,f/`: `/a\/b,c/|d|/e,f/`,
+		"a/b,c":            "a/b|c",
+		"":                 "",
+	}
+	for v, x := range tests {
+		if s := strings.Join(splitColumns(v), "|"); s != x {
+			t.Errorf("Split columns: %q: expecting %q, found %q", v, x, s)
+		}
+	}
+}
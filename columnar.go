@@ -0,0 +1,80 @@
+// Copyright (c) 2016, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io"
+	"math"
+	"strconv"
+)
+
+// Value kinds of the columns of columnar formats.
+const (
+	intKind    = iota // 64 bit integers
+	floatKind         // 64 bit floating point numbers
+	stringKind        // UTF-8 strings
+)
+
+// inferKind returns the kind of the values of a column, empty fields are
+// taken as nulls. Columns without values are strings.
+func inferKind(recs [][]string, col int) int {
+	kind := intKind
+	empty := true
+	for _, rec := range recs {
+		f := cell(rec, col)
+		if len(f) == 0 {
+			continue
+		}
+		empty = false
+		if kind == intKind {
+			if _, err := strconv.ParseInt(f, 10, 64); err == nil {
+				continue
+			}
+			kind = floatKind
+		}
+		if _, ok := getFieldValue(f).(float64); !ok {
+			return stringKind
+		}
+	}
+	if empty {
+		return stringKind
+	}
+	return kind
+}
+
+// formatFloat returns the text of a floating point value read from a
+// columnar format.
+func formatFloat(v float64) string {
+	if v == math.Trunc(v) && math.Abs(v) < 1e15 {
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// columnarWriter stores the records of a table to be written in a
+// columnar format.
+type columnarWriter struct {
+	out   io.Writer
+	recs  [][]string
+	write func(out io.Writer, header []string, kinds []int, recs [][]string) error
+}
+
+func (w *columnarWriter) Write(record []string) error {
+	w.recs = append(w.recs, append([]string{}, record...))
+	return nil
+}
+
+func (w *columnarWriter) Flush() error {
+	var header []string
+	var recs [][]string
+	if len(w.recs) > 0 {
+		header, recs = w.recs[0], w.recs[1:]
+	}
+	kinds := make([]int, len(header))
+	for i := range header {
+		kinds[i] = inferKind(recs, i)
+	}
+	return w.write(w.out, header, kinds, recs)
+}
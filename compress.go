@@ -0,0 +1,237 @@
+// Copyright (c) 2016, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// compression is a compression format of input and output files.
+type compression struct {
+	name  string
+	ext   string // file extension
+	magic string // first bytes of a compressed file
+
+	// header checks the bytes after the magic bytes, if not nil. Size
+	// is the number of bytes checked.
+	header func(b []byte) bool
+	size   int
+
+	newReader func(in io.Reader) (io.ReadCloser, error)
+	newWriter func(out io.Writer) (io.WriteCloser, error)
+}
+
+// compressions are the supported compression formats. The formats not
+// available in the standard library are handled with external programs.
+var compressions = []*compression{
+	&compression{
+		name:  "gzip",
+		ext:   ".gz",
+		magic: "\x1f\x8b",
+		newReader: func(in io.Reader) (io.ReadCloser, error) {
+			return gzip.NewReader(in)
+		},
+		newWriter: func(out io.Writer) (io.WriteCloser, error) {
+			return gzip.NewWriter(out), nil
+		},
+	},
+	&compression{
+		name:  "bzip2",
+		ext:   ".bz2",
+		magic: "BZh",
+		header: func(b []byte) bool {
+			// block size, and the magic number of the first block
+			return b[0] >= '1' && b[0] <= '9' && string(b[1:]) == "1AY&SY"
+		},
+		size: 7,
+		newReader: func(in io.Reader) (io.ReadCloser, error) {
+			return ioutil.NopCloser(bzip2.NewReader(in)), nil
+		},
+		newWriter: func(out io.Writer) (io.WriteCloser, error) {
+			return newExecWriter(out, "bzip2", "-c")
+		},
+	},
+	&compression{
+		name:  "zstd",
+		ext:   ".zst",
+		magic: "\x28\xb5\x2f\xfd",
+		newReader: func(in io.Reader) (io.ReadCloser, error) {
+			return newExecReader(in, "zstd", "-d", "-c")
+		},
+		newWriter: func(out io.Writer) (io.WriteCloser, error) {
+			return newExecWriter(out, "zstd", "-q", "-c")
+		},
+	},
+}
+
+// getCompression returns the compression of a file based on its
+// extension, and the file name without the compression extension. If the
+// file is not compressed, it returns nil.
+func getCompression(file string) (*compression, string) {
+	ext := strings.ToLower(filepath.Ext(file))
+	for _, c := range compressions {
+		if c.ext == ext {
+			return c, file[:len(file)-len(ext)]
+		}
+	}
+	return nil, file
+}
+
+// decompressInput returns a reader with the decompressed data of in. If c
+// is nil, the compression is detected from the first bytes of the data. If
+// the data is not compressed, the data is read as is.
+func decompressInput(in io.Reader, c *compression) (io.Reader, io.Closer, error) {
+	br := bufio.NewReader(in)
+	if c == nil {
+		for _, cp := range compressions {
+			if cp.detect(br) {
+				c = cp
+				break
+			}
+		}
+	}
+	if c == nil {
+		return br, nil, nil
+	}
+	r, err := c.newReader(br)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %v", c.name, err)
+	}
+	return r, r, nil
+}
+
+// detect returns true if the data of a reader starts with the header of
+// the compression format.
+func (c *compression) detect(br *bufio.Reader) bool {
+	m, err := br.Peek(len(c.magic) + c.size)
+	if err != nil || string(m[:len(c.magic)]) != c.magic {
+		return false
+	}
+	return c.header == nil || c.header(m[len(c.magic):])
+}
+
+// closers is a list of closers closed in order.
+type closers []io.Closer
+
+// Close closes all the closers, and returns the first error found.
+func (cs closers) Close() error {
+	var err error
+	for _, c := range cs {
+		if c == nil {
+			continue
+		}
+		if e := c.Close(); err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// execReader reads the output of an external program that filters an
+// input.
+type execReader struct {
+	cmd    *exec.Cmd
+	out    io.ReadCloser
+	stderr bytes.Buffer
+	done   bool
+}
+
+// newExecReader returns a reader with the output of a program that reads
+// from in.
+func newExecReader(in io.Reader, name string, args ...string) (io.ReadCloser, error) {
+	r := &execReader{cmd: exec.Command(name, args...)}
+	r.cmd.Stdin = in
+	r.cmd.Stderr = &r.stderr
+	out, err := r.cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	r.out = out
+	if err := r.cmd.Start(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *execReader) Read(p []byte) (int, error) {
+	n, err := r.out.Read(p)
+	if err == io.EOF && !r.done {
+		// the program must end without errors, otherwise the data is
+		// incomplete
+		r.done = true
+		if e := r.cmd.Wait(); e != nil {
+			return n, execError(r.cmd, e, &r.stderr)
+		}
+	}
+	return n, err
+}
+
+// Close stops the program, if it is still running.
+func (r *execReader) Close() error {
+	if r.done {
+		return nil
+	}
+	r.done = true
+	r.cmd.Process.Kill()
+	r.cmd.Wait()
+	return nil
+}
+
+// execWriter writes into the input of an external program that filters
+// an output.
+type execWriter struct {
+	cmd    *exec.Cmd
+	in     io.WriteCloser
+	stderr bytes.Buffer
+}
+
+// newExecWriter returns a writer that sends the data to a program that
+// writes into out.
+func newExecWriter(out io.Writer, name string, args ...string) (io.WriteCloser, error) {
+	w := &execWriter{cmd: exec.Command(name, args...)}
+	w.cmd.Stdout = out
+	w.cmd.Stderr = &w.stderr
+	in, err := w.cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	w.in = in
+	if err := w.cmd.Start(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *execWriter) Write(p []byte) (int, error) {
+	return w.in.Write(p)
+}
+
+// Close closes the input of the program, and waits for the program to
+// end.
+func (w *execWriter) Close() error {
+	err := w.in.Close()
+	if e := w.cmd.Wait(); e != nil {
+		return execError(w.cmd, e, &w.stderr)
+	}
+	return err
+}
+
+// execError returns the error of an external program.
+func execError(cmd *exec.Cmd, err error, stderr *bytes.Buffer) error {
+	name := filepath.Base(cmd.Path)
+	if msg := strings.TrimSpace(stderr.String()); len(msg) > 0 {
+		return fmt.Errorf("%s: %v: %s", name, err, msg)
+	}
+	return fmt.Errorf("%s: %v", name, err)
+}
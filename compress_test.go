@@ -0,0 +1,145 @@
+// Copyright (c) 2016, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGetCompression(t *testing.T) {
+	tests := []struct {
+		file, comp, base string
+	}{
+		{"data.tsv.gz", "gzip", "data.tsv"},
+		{"data.parquet.BZ2", "bzip2", "data.parquet"},
+		{"data.tsv.zst", "zstd", "data.tsv"},
+		{"data.tsv", "", "data.tsv"},
+	}
+	for _, ts := range tests {
+		c, base := getCompression(ts.file)
+		name := ""
+		if c != nil {
+			name = c.name
+		}
+		if name != ts.comp || base != ts.base {
+			t.Errorf("compression: %s: expecting %q %q, found %q %q", ts.file, ts.comp, ts.base, name, base)
+		}
+	}
+}
+
+func TestCompressedTable(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tables")
+	if err != nil {
+		t.Fatalf("compression: unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "data.json.gz")
+
+	rs := [][]string{
+		[]string{"Item", "Description"},
+		[]string{"1", "rubber gloves"},
+		[]string{"2", "test tubes"},
+	}
+	w, err := createTable(name)
+	if err != nil {
+		t.Fatalf("compression: unexpected error: %v", err)
+	}
+	for _, r := range rs {
+		w.Write(r)
+	}
+	if err := w.Close(); err != nil {
+		t.Errorf("compression: unexpected error: %v", err)
+	}
+	b, _ := ioutil.ReadFile(name)
+	if !bytes.HasPrefix(b, []byte("\x1f\x8b")) {
+		t.Errorf("compression: file %s is not compressed", name)
+	}
+
+	r, err := openTable(name)
+	if err != nil {
+		t.Fatalf("compression: unexpected error: %v", err)
+	}
+	defer r.Close()
+	for i, row := range rs {
+		rec, err := r.Read()
+		if err != nil {
+			t.Fatalf("compression: unexpected error: %v", err)
+		}
+		for j, v := range row {
+			if rec[j] != v {
+				t.Errorf("compression: expecting %q in row %d col %d, found %q", v, i, j, rec[j])
+			}
+		}
+	}
+}
+
+func TestDecompressMagic(t *testing.T) {
+	bz := "BZh91AY&SY<\xc7\xe9\xc9\x00\x00\x03I\x00\x0000\x000\x00 \x00\"\x18h0\x07@\x12\xc2\xeeH\xa7\n\x12\x07\x98\xfd9 "
+	for _, in := range []string{bz, "a\tb\n1\t2\n"} {
+		r, _, err := decompressInput(bytes.NewReader([]byte(in)), nil)
+		if err != nil {
+			t.Errorf("compression: unexpected error: %v", err)
+			continue
+		}
+		b, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Errorf("compression: unexpected error: %v", err)
+		}
+		if string(b) != "a\tb\n1\t2\n" {
+			t.Errorf("compression: expecting %q, found %q", "a\tb\n1\t2\n", b)
+		}
+	}
+}
+
+func TestPlainMagic(t *testing.T) {
+	// a table that starts with the magic bytes of a compression format
+	in := "BZhName\tValue\nx\t1\n"
+	r, _, err := decompressInput(bytes.NewReader([]byte(in)), nil)
+	if err != nil {
+		t.Fatalf("compression: unexpected error: %v", err)
+	}
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Errorf("compression: unexpected error: %v", err)
+	}
+	if string(b) != in {
+		t.Errorf("compression: expecting %q, found %q", in, b)
+	}
+}
+
+func TestDetectWithExtension(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tables")
+	if err != nil {
+		t.Fatalf("compression: unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// a gzipped file without the compression extension
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte("Item\tValue\nx\t1\n"))
+	zw.Close()
+	name := filepath.Join(dir, "data.tsv")
+	if err := ioutil.WriteFile(name, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("compression: unexpected error: %v", err)
+	}
+	r, err := openTable(name)
+	if err != nil {
+		t.Fatalf("compression: unexpected error: %v", err)
+	}
+	defer r.Close()
+	rec, err := r.Read()
+	if err != nil {
+		t.Fatalf("compression: unexpected error: %v", err)
+	}
+	if rec[0] != "Item" {
+		t.Errorf("compression: expecting header %q, found %q", "Item", rec[0])
+	}
+}
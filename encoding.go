@@ -0,0 +1,204 @@
+// Copyright (c) 2016, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Byte order marks.
+const (
+	utf8BOM    = "\xef\xbb\xbf"
+	utf16LEBOM = "\xff\xfe"
+	utf16BEBOM = "\xfe\xff"
+)
+
+// cp1252 are the characters of windows-1252 in the range 0x80-0x9F,
+// undefined characters are kept as the latin1 control characters.
+var cp1252 = [32]rune{
+	0x20AC, 0x0081, 0x201A, 0x0192, 0x201E, 0x2026, 0x2020, 0x2021,
+	0x02C6, 0x2030, 0x0160, 0x2039, 0x0152, 0x008D, 0x017D, 0x008F,
+	0x0090, 0x2018, 0x2019, 0x201C, 0x201D, 0x2022, 0x2013, 0x2014,
+	0x02DC, 0x2122, 0x0161, 0x203A, 0x0153, 0x009D, 0x017E, 0x0178,
+}
+
+// latin9 are the characters of iso-8859-15 that are different from
+// iso-8859-1.
+var latin9 = map[byte]rune{
+	0xA4: 0x20AC,
+	0xA6: 0x0160,
+	0xA8: 0x0161,
+	0xB4: 0x017D,
+	0xB8: 0x017E,
+	0xBC: 0x0152,
+	0xBD: 0x0153,
+	0xBE: 0x0178,
+}
+
+// decodeInput returns a reader that converts the input to UTF-8 from the
+// given encoding. If the encoding is empty or "auto", the encoding is
+// detected from the byte order mark, and if there is no mark, and the
+// start of the input is not valid UTF-8, the input is read as
+// windows-1252. A byte order mark at the start of the input is removed.
+func decodeInput(in io.Reader, enc string) (io.Reader, error) {
+	br := bufio.NewReader(in)
+	hasPrefix := func(p string) bool {
+		b, err := br.Peek(len(p))
+		return err == nil && string(b) == p
+	}
+	switch strings.Replace(strings.ToLower(enc), "_", "-", -1) {
+	case "", "auto":
+		switch {
+		case hasPrefix(utf8BOM):
+			br.Discard(len(utf8BOM))
+			return br, nil
+		case hasPrefix(utf16LEBOM):
+			return newUTF16Reader(br, false), nil
+		case hasPrefix(utf16BEBOM):
+			return newUTF16Reader(br, true), nil
+		}
+		b, _ := br.Peek(4096)
+		if !validUTF8Prefix(b) {
+			return newCharmapReader(br, cp1252Rune), nil
+		}
+		return br, nil
+	case "utf-8", "utf8":
+		if hasPrefix(utf8BOM) {
+			br.Discard(len(utf8BOM))
+		}
+		return br, nil
+	case "utf-16", "utf16":
+		return newUTF16Reader(br, !hasPrefix(utf16LEBOM)), nil
+	case "utf-16le", "utf16le":
+		return newUTF16Reader(br, false), nil
+	case "utf-16be", "utf16be":
+		return newUTF16Reader(br, true), nil
+	case "latin1", "latin-1", "iso-8859-1", "iso8859-1":
+		return newCharmapReader(br, func(b byte) rune { return rune(b) }), nil
+	case "latin9", "latin-9", "iso-8859-15", "iso8859-15":
+		return newCharmapReader(br, func(b byte) rune {
+			if r, ok := latin9[b]; ok {
+				return r
+			}
+			return rune(b)
+		}), nil
+	case "windows-1252", "cp1252":
+		return newCharmapReader(br, cp1252Rune), nil
+	}
+	return nil, fmt.Errorf("unknown encoding: %s", enc)
+}
+
+// validUTF8Prefix returns true if b is valid UTF-8, ignoring an incomplete
+// character at the end.
+func validUTF8Prefix(b []byte) bool {
+	for len(b) > 0 {
+		r, sz := utf8.DecodeRune(b)
+		if r == utf8.RuneError && sz == 1 {
+			return !utf8.FullRune(b)
+		}
+		b = b[sz:]
+	}
+	return true
+}
+
+// cp1252Rune returns the character of a windows-1252 byte.
+func cp1252Rune(b byte) rune {
+	if b >= 0x80 && b < 0xA0 {
+		return cp1252[b-0x80]
+	}
+	return rune(b)
+}
+
+// runeReader is a reader that writes as UTF-8 the characters returned by
+// a decoding function.
+type runeReader struct {
+	next  func() (rune, error)
+	first bool   // the first character was read
+	buf   []byte // pending output
+}
+
+func (r *runeReader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if len(r.buf) > 0 {
+			c := copy(p[n:], r.buf)
+			r.buf = r.buf[c:]
+			n += c
+			continue
+		}
+		c, err := r.next()
+		if err != nil {
+			if n > 0 && err == io.EOF {
+				return n, nil
+			}
+			return n, err
+		}
+		if !r.first {
+			r.first = true
+			if c == '\uFEFF' {
+				continue
+			}
+		}
+		var b [utf8.UTFMax]byte
+		r.buf = b[:utf8.EncodeRune(b[:], c)]
+	}
+	return n, nil
+}
+
+// newCharmapReader returns a reader for a single byte encoding.
+func newCharmapReader(br *bufio.Reader, cmap func(byte) rune) io.Reader {
+	return &runeReader{next: func() (rune, error) {
+		b, err := br.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		return cmap(b), nil
+	}}
+}
+
+// newUTF16Reader returns a reader for UTF-16 text.
+func newUTF16Reader(br *bufio.Reader, bigEndian bool) io.Reader {
+	unit := func() (rune, error) {
+		var b [2]byte
+		if _, err := io.ReadFull(br, b[:]); err != nil {
+			if err == io.ErrUnexpectedEOF {
+				return utf8.RuneError, nil
+			}
+			return 0, err
+		}
+		if bigEndian {
+			return rune(b[0])<<8 | rune(b[1]), nil
+		}
+		return rune(b[1])<<8 | rune(b[0]), nil
+	}
+	return &runeReader{next: func() (rune, error) {
+		c, err := unit()
+		if err != nil || !utf16.IsSurrogate(c) {
+			return c, err
+		}
+		if c >= 0xDC00 {
+			// unpaired low surrogate
+			return utf8.RuneError, nil
+		}
+		b, err := br.Peek(2)
+		if err != nil {
+			return utf8.RuneError, nil
+		}
+		lo := rune(b[1])<<8 | rune(b[0])
+		if bigEndian {
+			lo = rune(b[0])<<8 | rune(b[1])
+		}
+		if lo < 0xDC00 || lo > 0xDFFF {
+			return utf8.RuneError, nil
+		}
+		br.Discard(2)
+		return utf16.DecodeRune(c, lo), nil
+	}}
+}
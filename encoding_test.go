@@ -0,0 +1,46 @@
+// Copyright (c) 2016, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+func TestDecodeInput(t *testing.T) {
+	tests := []struct {
+		enc, in, out string
+	}{
+		{"", "\xef\xbb\xbfName\tLat\n", "Name\tLat\n"},
+		{"", "\xff\xfeN\x00\xe1\x00\n\x00", "Ná\n"},
+		{"", "\xfe\xff\x00N\x00\xe1\x00\n", "Ná\n"},
+		{"", "Ca\xf1ada\t\x80\n", "Cañada\t€\n"},
+		{"", "Cañada\n", "Cañada\n"},
+		{"utf-8", "\xef\xbb\xbfCañada", "Cañada"},
+		{"UTF-16LE", "=\xd8\x00\xdeA\x00", "\U0001F600A"},
+		{"utf-16", "\x00A\x00B", "AB"},
+		{"latin1", "Ca\xf1ada\t\x80", "Cañada\t\u0080"},
+		{"latin9", "\xa4\xbc", "€Œ"},
+		{"cp1252", "\x93a\x94", "“a”"},
+	}
+	for _, ts := range tests {
+		r, err := decodeInput(bytes.NewReader([]byte(ts.in)), ts.enc)
+		if err != nil {
+			t.Errorf("Encoding: %q: unexpected error: %v", ts.enc, err)
+			continue
+		}
+		b, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Errorf("Encoding: %q: unexpected error: %v", ts.enc, err)
+		}
+		if string(b) != ts.out {
+			t.Errorf("Encoding: %q: expecting %q, found %q", ts.enc, ts.out, b)
+		}
+	}
+	if _, err := decodeInput(bytes.NewReader(nil), "ebcdic"); err == nil {
+		t.Errorf("Encoding: expecting error on unknown encoding")
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright (c) 2016, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// fixedLines is the default number of lines used to detect the columns of
+// a fixed-width table.
+const fixedLines = 100
+
+// fixedReader reads a table with fixed-width columns.
+type fixedReader struct {
+	s      *bufio.Scanner
+	header []string
+	cols   [][2]int // start and end (exclusive) of each column, in runes
+	lines  []string // lines read during column detection
+}
+
+// newFixedReader returns a reader for fixed-width tables. The parameter
+// is either a column specification (e.g. "Name:1-10,Lat:11-18"), in which
+// case all the lines are data, or the number of lines used to detect the
+// columns, in which case the first line is the header.
+func newFixedReader(in io.Reader, param string) (recordReader, error) {
+	r := &fixedReader{s: bufio.NewScanner(in)}
+	r.s.Buffer(nil, 1024*1024)
+	n := fixedLines
+	if len(param) > 0 {
+		v, err := strconv.Atoi(param)
+		if err != nil {
+			return r, r.parseSpec(param)
+		}
+		if v < 1 {
+			return nil, fmt.Errorf("invalid number of fixed-width detection lines: %d", v)
+		}
+		n = v
+	}
+	for len(r.lines) < n {
+		ln, err := r.readLine()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		r.lines = append(r.lines, ln)
+	}
+	if len(r.lines) == 0 {
+		return r, nil
+	}
+	r.detect()
+	r.header = r.split(r.lines[0])
+	r.lines = r.lines[1:]
+	return r, nil
+}
+
+// parseSpec parses a column specification. Positions start at 1 and
+// include both ends. If the end is omitted, the column extends to the end
+// of the line.
+func (r *fixedReader) parseSpec(spec string) error {
+	for _, c := range strings.Split(spec, ",") {
+		i := strings.LastIndex(c, ":")
+		if i < 0 {
+			return fmt.Errorf("invalid fixed-width column: %s", c)
+		}
+		r.header = append(r.header, strings.TrimSpace(c[:i]))
+		pos := strings.SplitN(c[i+1:], "-", 2)
+		start, err := strconv.Atoi(pos[0])
+		if err != nil || start < 1 {
+			return fmt.Errorf("invalid fixed-width column: %s", c)
+		}
+		end := -1
+		if len(pos) == 2 && len(pos[1]) > 0 {
+			end, err = strconv.Atoi(pos[1])
+			if err != nil || end < start {
+				return fmt.Errorf("invalid fixed-width column: %s", c)
+			}
+		}
+		r.cols = append(r.cols, [2]int{start - 1, end})
+	}
+	return nil
+}
+
+// detect sets the columns from the gutters, i.e. the positions that are
+// blank in all the detection lines.
+func (r *fixedReader) detect() {
+	var blank []bool
+	for _, ln := range r.lines {
+		for i, c := range []rune(ln) {
+			if i >= len(blank) {
+				blank = append(blank, true)
+			}
+			if c != ' ' {
+				blank[i] = false
+			}
+		}
+	}
+	in := false
+	for i, b := range blank {
+		if b {
+			in = false
+			continue
+		}
+		if in {
+			continue
+		}
+		in = true
+		if len(r.cols) > 0 {
+			r.cols[len(r.cols)-1][1] = i
+		}
+		r.cols = append(r.cols, [2]int{i, -1})
+	}
+	if len(r.cols) > 0 {
+		r.cols[0][0] = 0
+	}
+}
+
+// readLine reads a non blank line.
+func (r *fixedReader) readLine() (string, error) {
+	for r.s.Scan() {
+		ln := strings.TrimRight(r.s.Text(), "\r")
+		if len(strings.TrimSpace(ln)) == 0 {
+			continue
+		}
+		return ln, nil
+	}
+	if err := r.s.Err(); err != nil {
+		return "", err
+	}
+	return "", io.EOF
+}
+
+// split splits a line into fields.
+func (r *fixedReader) split(ln string) []string {
+	rs := []rune(ln)
+	rec := make([]string, len(r.cols))
+	for i, c := range r.cols {
+		if c[0] >= len(rs) {
+			continue
+		}
+		end := c[1]
+		if end == -1 || end > len(rs) {
+			end = len(rs)
+		}
+		rec[i] = strings.TrimSpace(string(rs[c[0]:end]))
+	}
+	return rec
+}
+
+func (r *fixedReader) Read() ([]string, error) {
+	if r.header != nil {
+		h := r.header
+		r.header = nil
+		return h, nil
+	}
+	if len(r.lines) > 0 {
+		ln := r.lines[0]
+		r.lines = r.lines[1:]
+		return r.split(ln), nil
+	}
+	ln, err := r.readLine()
+	if err != nil {
+		return nil, err
+	}
+	return r.split(ln), nil
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2016, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+var fixedBlob = `
+Station      Lat      Lon
+San Miguel   -26.83   -65.20
+Tafi         -26.85   -65.71
+
+Amaicha del  -26.59   -65.92
+`
+
+func testFixedRead(t *testing.T, r recordReader, rs [][]string) {
+	for i := 0; ; i++ {
+		row, err := r.Read()
+		if err != nil {
+			if err == io.EOF {
+				if i != len(rs) {
+					t.Errorf("Fixed: expecting %d records, found %d", len(rs), i)
+				}
+				break
+			}
+			t.Errorf("Fixed: unexpected error: %v", err)
+			break
+		}
+		if i >= len(rs) {
+			continue
+		}
+		if len(row) != len(rs[i]) {
+			t.Errorf("Fixed: expecting %d fields, found %d (row %d)", len(rs[i]), len(row), i)
+			continue
+		}
+		for j, v := range rs[i] {
+			if row[j] != v {
+				t.Errorf("Fixed: expecting %q in row %d col %d, found %q", v, i, j, row[j])
+			}
+		}
+	}
+}
+
+func TestFixedDetect(t *testing.T) {
+	r, err := newFixedReader(strings.NewReader(fixedBlob), "")
+	if err != nil {
+		t.Errorf("Fixed: unexpected error: %v", err)
+	}
+	rs := [][]string{
+		[]string{"Station", "Lat", "Lon"},
+		[]string{"San Miguel", "-26.83", "-65.20"},
+		[]string{"Tafi", "-26.85", "-65.71"},
+		[]string{"Amaicha del", "-26.59", "-65.92"},
+	}
+	testFixedRead(t, r, rs)
+
+	// detection with the first two lines
+	r, err = newFixedReader(strings.NewReader(fixedBlob), "2")
+	if err != nil {
+		t.Errorf("Fixed: unexpected error: %v", err)
+	}
+	testFixedRead(t, r, rs)
+}
+
+func TestFixedSpec(t *testing.T) {
+	r, err := newFixedReader(strings.NewReader(fixedBlob), "Name:1-10,Lat:14-19,Lon:23-")
+	if err != nil {
+		t.Errorf("Fixed: unexpected error: %v", err)
+	}
+	rs := [][]string{
+		[]string{"Name", "Lat", "Lon"},
+		[]string{"Station", "Lat", "Lon"},
+		[]string{"San Miguel", "-26.83", "-65.20"},
+		[]string{"Tafi", "-26.85", "-65.71"},
+		[]string{"Amaicha de", "-26.59", "-65.92"},
+	}
+	testFixedRead(t, r, rs)
+
+	if _, err := newFixedReader(strings.NewReader(fixedBlob), "Name:10-1"); err == nil {
+		t.Errorf("Fixed: expecting error on invalid specification")
+	}
+}
@@ -0,0 +1,252 @@
+// Copyright (c) 2016, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"io"
+
+	"github.com/js-arias/cmdapp"
+)
+
+var headCmd = &cmdapp.Command{
+	Run: headRun,
+	UsageLine: `head [-f <char>] [--dups <policy>] [--encoding <name>]
+	[--from <format>] [-i|--input <file>] [-n|--no-header]
+	[-o|--output <file>] [--ragged <policy>] [--schema <file>]
+	[--to <format>] [-r|--rows <number>] [--skip <number>]`,
+	Short: "outputs the first rows of a table",
+	Long: `
+Command head outputs a table with the header and the first rows of the input
+table. By default the first 10 rows are printed.
+
+Options are:
+
+    -f <char>
+      Sets the field separation character. By default the value is the tab
+      character.
+
+    --dups <policy>
+      Sets how to handle duplicated column names in the header, one of
+      warn (the default), error, or rename. See 'tables help formats'.
+
+    --encoding <name>
+      Sets the character encoding of the input. By default the encoding
+      is detected from the input. See 'tables help formats' for the
+      available encodings.
+
+    --from <format>
+      Sets the format of the input table. By default the format is
+      detected from the extension of the input file, or it is a delimited
+      text table. See 'tables help formats' for the available formats.
+
+    -i <file>
+    --input <file>
+      Read the table from <file> instead of stdin.
+
+    -n
+    --no-header
+      If set, the table will be printed without a header.
+
+    -o <file>
+    --output <file>
+      Write the resulting table to <file> instead of stdout.
+
+    --ragged <policy>
+      Sets how to handle records with a different number of fields than
+      the header, one of error (the default), pad, truncate, or skip. See
+      'tables help formats'.
+
+    --schema <file>
+      Read the types of the columns of the input from a schema file. See
+      'tables help schema'.
+
+    --to <format>
+      Sets the format of the output table. By default the format is
+      detected from the extension of the output file, or it is a delimited
+      text table. See 'tables help formats' for the available formats.
+
+    -r <number>
+    --rows <number>
+      Sets the number of rows to print. The default is 10.
+
+    --skip <number>
+      Skips the given number of rows at the start of the table, before
+      printing the rows.
+	`,
+}
+
+var tailCmd = &cmdapp.Command{
+	Run: tailRun,
+	UsageLine: `tail [-f <char>] [--dups <policy>] [--encoding <name>]
+	[--from <format>] [-i|--input <file>] [-n|--no-header]
+	[-o|--output <file>] [--ragged <policy>] [--schema <file>]
+	[--to <format>] [-r|--rows <number>] [--skip <number>]`,
+	Short: "outputs the last rows of a table",
+	Long: `
+Command tail outputs a table with the header and the last rows of the input
+table. By default the last 10 rows are printed.
+
+Options are:
+
+    -f <char>
+      Sets the field separation character. By default the value is the tab
+      character.
+
+    --dups <policy>
+      Sets how to handle duplicated column names in the header, one of
+      warn (the default), error, or rename. See 'tables help formats'.
+
+    --encoding <name>
+      Sets the character encoding of the input. By default the encoding
+      is detected from the input. See 'tables help formats' for the
+      available encodings.
+
+    --from <format>
+      Sets the format of the input table. By default the format is
+      detected from the extension of the input file, or it is a delimited
+      text table. See 'tables help formats' for the available formats.
+
+    -i <file>
+    --input <file>
+      Read the table from <file> instead of stdin.
+
+    -n
+    --no-header
+      If set, the table will be printed without a header.
+
+    -o <file>
+    --output <file>
+      Write the resulting table to <file> instead of stdout.
+
+    --ragged <policy>
+      Sets how to handle records with a different number of fields than
+      the header, one of error (the default), pad, truncate, or skip. See
+      'tables help formats'.
+
+    --schema <file>
+      Read the types of the columns of the input from a schema file. See
+      'tables help schema'.
+
+    --to <format>
+      Sets the format of the output table. By default the format is
+      detected from the extension of the output file, or it is a delimited
+      text table. See 'tables help formats' for the available formats.
+
+    -r <number>
+    --rows <number>
+      Sets the number of rows to print. The default is 10.
+
+    --skip <number>
+      Skips the given number of rows at the end of the table, i.e. the
+      printed rows are the ones before the skipped rows.
+	`,
+}
+
+var numRows int  // set the number of rows, -r|--rows
+var skipRows int // set the number of skipped rows, --skip
+
+func init() {
+	initCommonFlags(headCmd)
+	headCmd.Flag.IntVar(&numRows, "rows", 10, "")
+	headCmd.Flag.IntVar(&numRows, "r", 10, "")
+	headCmd.Flag.IntVar(&skipRows, "skip", 0, "")
+
+	initCommonFlags(tailCmd)
+	tailCmd.Flag.IntVar(&numRows, "rows", 10, "")
+	tailCmd.Flag.IntVar(&numRows, "r", 10, "")
+	tailCmd.Flag.IntVar(&skipRows, "skip", 0, "")
+}
+
+func headRun(c *cmdapp.Command, args []string) error {
+	return headTailRun(headRows)
+}
+
+func tailRun(c *cmdapp.Command, args []string) error {
+	return headTailRun(tailRows)
+}
+
+// headTailRun runs the head or tail command, using fn to select the rows.
+func headTailRun(fn func(r recordReader, skip, n int, w rowWriter) error) error {
+	if numRows < 0 || skipRows < 0 {
+		return errors.New("the number of rows must not be negative")
+	}
+	r, err := openInput()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	w, err := openOutput()
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	header, err := r.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+	if err := w.Write(header); err != nil {
+		return err
+	}
+	if err := fn(r, skipRows, numRows, w); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// headRows writes n rows of a table, after skipping the first skip rows.
+// The rows after the written rows are not read.
+func headRows(r recordReader, skip, n int, w rowWriter) error {
+	for i := 0; i < skip+n; i++ {
+		row, err := r.Read()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if i < skip {
+			continue
+		}
+		if err := w.Write(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tailRows writes the last n rows of a table, ignoring the last skip
+// rows. The rows are stored in a ring buffer, so only skip+n rows are kept
+// in memory.
+func tailRows(r recordReader, skip, n int, w rowWriter) error {
+	ring := make([][]string, skip+n)
+	total := 0
+	for {
+		row, err := r.Read()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return err
+		}
+		if len(ring) > 0 {
+			ring[total%len(ring)] = row
+		}
+		total++
+	}
+	start := total - skip - n
+	if start < 0 {
+		start = 0
+	}
+	for i := start; i < total-skip; i++ {
+		if err := w.Write(ring[i%len(ring)]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
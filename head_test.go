@@ -0,0 +1,49 @@
+// Copyright (c) 2016, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/csv"
+	"strings"
+	"testing"
+)
+
+func TestHeadTail(t *testing.T) {
+	tests := []struct {
+		tail    bool
+		skip, n int
+		items   string
+	}{
+		{false, 0, 3, "1 2 3"},
+		{false, 5, 10, "6 7"},
+		{false, 7, 1, ""},
+		{true, 0, 3, "5 6 7"},
+		{true, 2, 2, "4 5"},
+		{true, 0, 10, "1 2 3 4 5 6 7"},
+		{true, 6, 3, "1"},
+		{true, 0, 0, ""},
+	}
+	for _, ts := range tests {
+		// cols blob is in cols_test.go
+		r := csv.NewReader(strings.NewReader(colsBlob))
+		r.Comma = '\t'
+		r.Read()
+		w := &memWriter{}
+		fn := headRows
+		if ts.tail {
+			fn = tailRows
+		}
+		if err := fn(r, ts.skip, ts.n, w); err != nil {
+			t.Errorf("Head: unexpected error: %v", err)
+		}
+		var items []string
+		for _, rec := range w.recs {
+			items = append(items, rec[0])
+		}
+		if s := strings.Join(items, " "); s != ts.items {
+			t.Errorf("Head: tail %v skip %d rows %d: expecting %q, found %q", ts.tail, ts.skip, ts.n, ts.items, s)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+// Copyright (c) 2016, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+// newJSONReader returns a reader for JSON and NDJSON tables. The table is
+// read from a JSON array of objects, or from a stream of JSON objects (one
+// object per line). The header of the table is the union of the keys of all
+// the objects.
+func newJSONReader(in io.Reader, param string) (recordReader, error) {
+	dec := json.NewDecoder(in)
+	var objs []json.RawMessage
+	for {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		if raw[0] == '[' {
+			var arr []json.RawMessage
+			if err := json.Unmarshal(raw, &arr); err != nil {
+				return nil, err
+			}
+			objs = append(objs, arr...)
+			continue
+		}
+		objs = append(objs, raw)
+	}
+
+	var header []string
+	index := make(map[string]int)
+	var rows []map[string]string
+	for i, o := range objs {
+		row := make(map[string]string)
+		if err := flattenJSON(o, "", row, func(k string) {
+			if _, ok := index[k]; ok {
+				return
+			}
+			index[k] = len(header)
+			header = append(header, k)
+		}); err != nil {
+			re, ok := err.(*readError)
+			if !ok {
+				re = &readError{err: err}
+			}
+			re.pos = fmt.Sprintf("object %d", i+1)
+			return nil, re
+		}
+		rows = append(rows, row)
+	}
+
+	r := &memReader{}
+	if len(header) == 0 {
+		return r, nil
+	}
+	r.recs = append(r.recs, header)
+	for _, row := range rows {
+		rec := make([]string, len(header))
+		for k, v := range row {
+			rec[index[k]] = v
+		}
+		r.recs = append(r.recs, rec)
+	}
+	return r, nil
+}
+
+// flattenJSON stores the fields of a JSON object in row. The keys of
+// nested objects are joined with a dot. Each key found is passed to the
+// key function in the order they are found in the object.
+func flattenJSON(raw json.RawMessage, prefix string, row map[string]string, key func(string)) error {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := tok.(json.Delim); !ok || d != '{' {
+		return errors.New("expecting a JSON object")
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		k := prefix + tok.(string)
+		var v json.RawMessage
+		if err := dec.Decode(&v); err != nil {
+			return &readError{column: k, err: err}
+		}
+		if v[0] == '{' {
+			if err := flattenJSON(v, k+".", row, key); err != nil {
+				return err
+			}
+			continue
+		}
+		key(k)
+		switch v[0] {
+		case '"':
+			var s string
+			if err := json.Unmarshal(v, &s); err != nil {
+				return &readError{column: k, err: err}
+			}
+			row[k] = s
+		case 'n':
+			row[k] = ""
+		case '[':
+			var b bytes.Buffer
+			if err := json.Compact(&b, v); err != nil {
+				return &readError{column: k, err: err}
+			}
+			row[k] = b.String()
+		default:
+			// numbers and booleans
+			row[k] = string(v)
+		}
+	}
+	return nil
+}
+
+// jsonWriter writes a table as a JSON array of objects, or as a stream of
+// objects, one per line, keyed by the header names.
+type jsonWriter struct {
+	w      *bufio.Writer
+	header []string
+	lines  bool // one object per line
+	n      int  // objects written
+}
+
+// newJSONWriter returns a writer for JSON tables.
+func newJSONWriter(out io.Writer, param string) recordWriter {
+	return &jsonWriter{w: bufio.NewWriter(out)}
+}
+
+// newNDJSONWriter returns a writer for NDJSON tables.
+func newNDJSONWriter(out io.Writer, param string) recordWriter {
+	return &jsonWriter{w: bufio.NewWriter(out), lines: true}
+}
+
+func (w *jsonWriter) Write(record []string) error {
+	if w.header == nil {
+		w.header = append([]string{}, record...)
+		return nil
+	}
+	if !w.lines {
+		if w.n == 0 {
+			w.w.WriteString("[\n")
+		} else {
+			w.w.WriteString(",\n")
+		}
+	}
+	w.n++
+	w.w.WriteByte('{')
+	for i, h := range w.header {
+		if i > 0 {
+			w.w.WriteByte(',')
+		}
+		w.w.Write(jsonString(h))
+		w.w.WriteByte(':')
+		v := ""
+		if i < len(record) {
+			v = record[i]
+		}
+		w.w.Write(jsonValue(v))
+	}
+	w.w.WriteByte('}')
+	if w.lines {
+		w.w.WriteByte('\n')
+	}
+	return nil
+}
+
+func (w *jsonWriter) Flush() error {
+	if !w.lines {
+		if w.n == 0 {
+			w.w.WriteString("[]\n")
+		} else {
+			w.w.WriteString("\n]\n")
+		}
+	}
+	return w.w.Flush()
+}
+
+// jsonString returns a string encoded as JSON.
+func jsonString(s string) []byte {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return bytes.TrimSpace(b.Bytes())
+}
+
+// jsonValue returns a field encoded as JSON. Numeric fields (as defined by
+// getFieldValue) are encoded as numbers, and empty fields as null.
+func jsonValue(field string) []byte {
+	if len(field) == 0 {
+		return []byte("null")
+	}
+	v, ok := getFieldValue(field).(float64)
+	if !ok || math.IsInf(v, 0) || math.IsNaN(v) {
+		return jsonString(field)
+	}
+	if json.Valid([]byte(field)) {
+		return []byte(field)
+	}
+	return []byte(strconv.FormatFloat(v, 'g', -1, 64))
+}
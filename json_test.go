@@ -0,0 +1,92 @@
+// Copyright (c) 2016, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestJSONRead(t *testing.T) {
+	blobs := []string{
+		`[{"Item": 1, "Name": "gloves"}, {"Item": 2, "Loc": {"Lat": -26.8}, "Name": null}]`,
+		`{"Item": 1, "Name": "gloves"}
+{"Item": 2, "Loc": {"Lat": -26.8}, "Name": null}`,
+	}
+	rs := [][]string{
+		[]string{"Item", "Name", "Loc.Lat"},
+		[]string{"1", "gloves", ""},
+		[]string{"2", "", "-26.8"},
+	}
+	for _, b := range blobs {
+		r, err := newJSONReader(strings.NewReader(b), "")
+		if err != nil {
+			t.Errorf("JSON: unexpected error: %v", err)
+			continue
+		}
+		for i := 0; ; i++ {
+			row, err := r.Read()
+			if err != nil {
+				if err == io.EOF {
+					if i != len(rs) {
+						t.Errorf("JSON: expecting %d records, found %d", len(rs), i)
+					}
+					break
+				}
+				t.Errorf("JSON: unexpected error: %v", err)
+				break
+			}
+			if len(row) != len(rs[i]) {
+				t.Errorf("JSON: expecting %d fields, found %d (row %d)", len(rs[i]), len(row), i)
+				continue
+			}
+			for j, v := range rs[i] {
+				if row[j] != v {
+					t.Errorf("JSON: expecting %q in row %d col %d, found %q", v, i, j, row[j])
+				}
+			}
+		}
+	}
+
+	_, err := newJSONReader(strings.NewReader(`[{"Item": 1}, 2]`), "")
+	if re, ok := err.(*readError); !ok || re.pos != "object 2" {
+		t.Errorf("JSON: expecting error in object 2, found %v", err)
+	}
+}
+
+func TestJSONWrite(t *testing.T) {
+	rs := [][]string{
+		[]string{"Item", "Cost", "Description"},
+		[]string{"1", ".5", "rubber gloves"},
+		[]string{"2", "", "12"},
+	}
+	var b bytes.Buffer
+	w := newJSONWriter(&b, "")
+	for _, r := range rs {
+		w.Write(r)
+	}
+	w.Flush()
+	exp := `[
+{"Item":1,"Cost":0.5,"Description":"rubber gloves"},
+{"Item":2,"Cost":null,"Description":12}
+]
+`
+	if b.String() != exp {
+		t.Errorf("JSON: expecting:\n%s\nfound:\n%s", exp, b.String())
+	}
+
+	b.Reset()
+	w = newNDJSONWriter(&b, "")
+	for _, r := range rs[:2] {
+		w.Write(r)
+	}
+	w.Flush()
+	exp = `{"Item":1,"Cost":0.5,"Description":"rubber gloves"}` + "\n"
+	if b.String() != exp {
+		t.Errorf("NDJSON: expecting:\n%s\nfound:\n%s", exp, b.String())
+	}
+}
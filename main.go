@@ -11,9 +11,23 @@ import (
 func init() {
 	cmdapp.Short = "Tables is a tool for management of text based tables."
 	cmdapp.Commands = []*cmdapp.Command{
+		catCmd,
 		colsCmd,
+		headCmd,
+		meltCmd,
+		numberCmd,
+		pivotCmd,
+		renameCmd,
 		rowsCmd,
+		sampleCmd,
+		schemaCmd,
+		splitCmd,
 		statsCmd,
+		tailCmd,
+		transposeCmd,
+		validateCmd,
+
+		formatsHelp,
 	}
 }
 
@@ -23,22 +37,34 @@ func main() {
 
 // general flags used by most commands
 var (
-	delim  string // set field delimitator, -f
-	input  string // set input file, -i|--input
-	invert bool   // invert command behavior, -v|--invert
-	noHead bool   // set the header output, -n|--no-header
-	output string // set output file, -o|--output
+	delim    string // set field delimitator, -f
+	dups     string // set duplicated columns policy, --dups
+	encoding string // set input encoding, --encoding
+	from     string // set input format, --from
+	input    string // set input file, -i|--input
+	invert   bool   // invert command behavior, -v|--invert
+	noHead   bool   // set the header output, -n|--no-header
+	output   string // set output file, -o|--output
+	ragged   string // set ragged rows policy, --ragged
+	schema   string // set schema file, --schema
+	to       string // set output format, --to
 )
 
 // initialize general flags.
 func initCommonFlags(c *cmdapp.Command) {
 	c.Flag.StringVar(&delim, "f", "\t", "")
+	c.Flag.StringVar(&dups, "dups", "", "")
+	c.Flag.StringVar(&encoding, "encoding", "", "")
+	c.Flag.StringVar(&from, "from", "", "")
 	c.Flag.StringVar(&input, "input", "", "")
 	c.Flag.StringVar(&input, "i", "", "")
 	c.Flag.BoolVar(&noHead, "no-header", false, "")
 	c.Flag.BoolVar(&noHead, "n", false, "")
 	c.Flag.StringVar(&output, "output", "", "")
 	c.Flag.StringVar(&output, "o", "", "")
+	c.Flag.StringVar(&ragged, "ragged", "", "")
+	c.Flag.StringVar(&schema, "schema", "", "")
+	c.Flag.StringVar(&to, "to", "", "")
 	c.Flag.BoolVar(&invert, "invert", false, "")
 	c.Flag.BoolVar(&invert, "v", false, "")
 }
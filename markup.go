@@ -0,0 +1,194 @@
+// Copyright (c) 2016, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"html"
+	"io"
+	"strings"
+)
+
+// markupWriter writes a table in a markup language (Markdown, HTML, or
+// LaTeX). As the alignment of the columns depends on all the rows, the
+// table is written when Flush is called.
+type markupWriter struct {
+	out    io.Writer
+	head   bool   // the first record is the header
+	param  string // format parameter
+	recs   [][]string
+	render func(w *bufio.Writer, m *markupWriter, nums []bool)
+}
+
+// newMarkdownWriter returns a writer for GitHub flavoured Markdown tables.
+func newMarkdownWriter(out io.Writer, param string) recordWriter {
+	return &markupWriter{out: out, head: true, render: renderMarkdown}
+}
+
+// newHTMLWriter returns a writer for HTML tables, the parameter is the CSS
+// class of the table.
+func newHTMLWriter(out io.Writer, param string) recordWriter {
+	return &markupWriter{out: out, head: !noHead, param: param, render: renderHTML}
+}
+
+// newLaTeXWriter returns a writer for LaTeX tabular tables.
+func newLaTeXWriter(out io.Writer, param string) recordWriter {
+	return &markupWriter{out: out, head: !noHead, render: renderLaTeX}
+}
+
+// newBooktabsWriter returns a writer for LaTeX tabular tables using the
+// rules of the booktabs package.
+func newBooktabsWriter(out io.Writer, param string) recordWriter {
+	return &markupWriter{out: out, head: !noHead, param: "booktabs", render: renderLaTeX}
+}
+
+func (w *markupWriter) Write(record []string) error {
+	w.recs = append(w.recs, append([]string{}, record...))
+	return nil
+}
+
+func (w *markupWriter) Flush() error {
+	bw := bufio.NewWriter(w.out)
+	w.render(bw, w, w.numericColumns())
+	return bw.Flush()
+}
+
+// numCols returns the number of columns of the table.
+func (w *markupWriter) numCols() int {
+	n := 0
+	for _, rec := range w.recs {
+		if len(rec) > n {
+			n = len(rec)
+		}
+	}
+	return n
+}
+
+// numericColumns returns true for each column in which all non-empty
+// fields are numbers.
+func (w *markupWriter) numericColumns() []bool {
+	nums := make([]bool, w.numCols())
+	for i := range nums {
+		for j, rec := range w.recs {
+			if j == 0 && w.head {
+				continue
+			}
+			f := cell(rec, i)
+			if len(f) == 0 {
+				continue
+			}
+			if _, ok := getFieldValue(f).(float64); !ok {
+				nums[i] = false
+				break
+			}
+			nums[i] = true
+		}
+	}
+	return nums
+}
+
+// renderMarkdown writes a Markdown table.
+func renderMarkdown(w *bufio.Writer, m *markupWriter, nums []bool) {
+	esc := strings.NewReplacer("|", "\\|", "\r\n", "<br>", "\n", "<br>")
+	row := func(rec []string) {
+		w.WriteString("|")
+		for i := range nums {
+			w.WriteString(" " + esc.Replace(cell(rec, i)) + " |")
+		}
+		w.WriteString("\n")
+	}
+	for i, rec := range m.recs {
+		row(rec)
+		if i > 0 {
+			continue
+		}
+		w.WriteString("|")
+		for _, n := range nums {
+			if n {
+				w.WriteString(" ---: |")
+			} else {
+				w.WriteString(" --- |")
+			}
+		}
+		w.WriteString("\n")
+	}
+}
+
+// renderHTML writes an HTML table.
+func renderHTML(w *bufio.Writer, m *markupWriter, nums []bool) {
+	row := func(rec []string, tag string) {
+		w.WriteString("<tr>")
+		for i, n := range nums {
+			w.WriteString("<" + tag)
+			if n {
+				w.WriteString(` style="text-align: right"`)
+			}
+			w.WriteString(">" + html.EscapeString(cell(rec, i)) + "</" + tag + ">")
+		}
+		w.WriteString("</tr>\n")
+	}
+	w.WriteString("<table")
+	if len(m.param) > 0 {
+		w.WriteString(` class="` + html.EscapeString(m.param) + `"`)
+	}
+	w.WriteString(">\n")
+	recs := m.recs
+	if m.head && len(recs) > 0 {
+		w.WriteString("<thead>\n")
+		row(recs[0], "th")
+		w.WriteString("</thead>\n")
+		recs = recs[1:]
+	}
+	w.WriteString("<tbody>\n")
+	for _, rec := range recs {
+		row(rec, "td")
+	}
+	w.WriteString("</tbody>\n</table>\n")
+}
+
+// latexEscape escapes the special characters of LaTeX.
+var latexEscape = strings.NewReplacer(
+	`\`, `\textbackslash{}`,
+	`&`, `\&`,
+	`%`, `\%`,
+	`$`, `\$`,
+	`#`, `\#`,
+	`_`, `\_`,
+	`{`, `\{`,
+	`}`, `\}`,
+	`~`, `\textasciitilde{}`,
+	`^`, `\textasciicircum{}`,
+	"\n", " ",
+)
+
+// renderLaTeX writes a LaTeX tabular table.
+func renderLaTeX(w *bufio.Writer, m *markupWriter, nums []bool) {
+	top, mid, bottom := `\hline`, `\hline`, `\hline`
+	if m.param == "booktabs" {
+		top, mid, bottom = `\toprule`, `\midrule`, `\bottomrule`
+	}
+	w.WriteString(`\begin{tabular}{`)
+	for _, n := range nums {
+		if n {
+			w.WriteString("r")
+		} else {
+			w.WriteString("l")
+		}
+	}
+	w.WriteString("}\n" + top + "\n")
+	for i, rec := range m.recs {
+		for j := range nums {
+			if j > 0 {
+				w.WriteString(" & ")
+			}
+			w.WriteString(latexEscape.Replace(cell(rec, j)))
+		}
+		w.WriteString(` \\` + "\n")
+		if i == 0 && m.head && len(m.recs) > 1 {
+			w.WriteString(mid + "\n")
+		}
+	}
+	w.WriteString(bottom + "\n" + `\end{tabular}` + "\n")
+}
@@ -0,0 +1,69 @@
+// Copyright (c) 2016, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+var markupRecs = [][]string{
+	[]string{"Item", "Cost", "Description"},
+	[]string{"1", "50", "rubber & gloves"},
+	[]string{"2", "", "test|tubes"},
+}
+
+func testMarkup(t *testing.T, name string, w recordWriter, b *bytes.Buffer, exp string) {
+	for _, r := range markupRecs {
+		w.Write(r)
+	}
+	if err := w.Flush(); err != nil {
+		t.Errorf("%s: unexpected error: %v", name, err)
+	}
+	if b.String() != exp {
+		t.Errorf("%s: expecting:\n%s\nfound:\n%s", name, exp, b.String())
+	}
+}
+
+func TestMarkdownWrite(t *testing.T) {
+	var b bytes.Buffer
+	exp := `| Item | Cost | Description |
+| ---: | ---: | --- |
+| 1 | 50 | rubber & gloves |
+| 2 |  | test\|tubes |
+`
+	testMarkup(t, "Markdown", newMarkdownWriter(&b, ""), &b, exp)
+}
+
+func TestHTMLWrite(t *testing.T) {
+	var b bytes.Buffer
+	exp := `<table class="data">
+<thead>
+<tr><th style="text-align: right">Item</th><th style="text-align: right">Cost</th><th>Description</th></tr>
+</thead>
+<tbody>
+<tr><td style="text-align: right">1</td><td style="text-align: right">50</td><td>rubber &amp; gloves</td></tr>
+<tr><td style="text-align: right">2</td><td style="text-align: right"></td><td>test|tubes</td></tr>
+</tbody>
+</table>
+`
+	w := &markupWriter{out: &b, head: true, param: "data", render: renderHTML}
+	testMarkup(t, "HTML", w, &b, exp)
+}
+
+func TestLaTeXWrite(t *testing.T) {
+	var b bytes.Buffer
+	exp := `\begin{tabular}{rrl}
+\toprule
+Item & Cost & Description \\
+\midrule
+1 & 50 & rubber \& gloves \\
+2 &  & test|tubes \\
+\bottomrule
+\end{tabular}
+`
+	w := &markupWriter{out: &b, head: true, param: "booktabs", render: renderLaTeX}
+	testMarkup(t, "LaTeX", w, &b, exp)
+}
@@ -0,0 +1,233 @@
+// Copyright (c) 2016, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/js-arias/cmdapp"
+)
+
+var meltCmd = &cmdapp.Command{
+	Run: meltRun,
+	UsageLine: `melt [-f <char>] [--dups <policy>] [--encoding <name>]
+	[--from <format>] [-i|--input <file>] [-n|--no-header]
+	[-o|--output <file>] [--ragged <policy>] [--schema <file>]
+	[--to <format>] [--values <column>[,<column>...]]
+	[--var-name <name>] [--value-name <name>] [--drop-empty] <id>...`,
+	Short: "converts a table from wide to long form",
+	Long: `
+Command melt reads a table in wide form, and outputs a table in long form,
+i.e. the inverse of the pivot command. For each row of the input table, and
+each value column, it outputs a row with the identifier columns, a column
+with the name of the value column (by default, "variable"), and a column
+with the value (by default, "value").
+
+The identifier columns are given as arguments. By default, the value
+columns are all the other columns of the table.
+
+For example, a matrix with a row for each site (in the column Site), and a
+column for each species, can be converted to a table with the columns
+Site, Species, and Count, with:
+
+    tables melt --var-name Species --value-name Count --drop-empty Site
+
+Options are:
+
+    -f <char>
+      Sets the field separation character. By default the value is the tab
+      character.
+
+    --dups <policy>
+      Sets how to handle duplicated column names in the header, one of
+      warn (the default), error, or rename. See 'tables help formats'.
+
+    --encoding <name>
+      Sets the character encoding of the input. By default the encoding
+      is detected from the input. See 'tables help formats' for the
+      available encodings.
+
+    --from <format>
+      Sets the format of the input table. By default the format is
+      detected from the extension of the input file, or it is a delimited
+      text table. See 'tables help formats' for the available formats.
+
+    -i <file>
+    --input <file>
+      Read the table from <file> instead of stdin.
+
+    -n
+    --no-header
+      If set, the table will be printed without a header.
+
+    -o <file>
+    --output <file>
+      Write the resulting table to <file> instead of stdout.
+
+    --ragged <policy>
+      Sets how to handle records with a different number of fields than
+      the header, one of error (the default), pad, truncate, or skip. See
+      'tables help formats'.
+
+    --schema <file>
+      Read the types of the columns of the input from a schema file. See
+      'tables help schema'.
+
+    --to <format>
+      Sets the format of the output table. By default the format is
+      detected from the extension of the output file, or it is a delimited
+      text table. See 'tables help formats' for the available formats.
+
+    --values <column>[,<column>...]
+      Sets the value columns, as a comma separated list of columns
+      selected as in 'tables help cols' (e.g. sp1..sp20, /^sp/, or
+      sp1,sp7,sp9). Commas in a regular expression do not separate the
+      columns. Identifier columns are never used as value columns.
+
+    --var-name <name>
+      Sets the name of the column with the names of the value columns. By
+      default it is "variable".
+
+    --value-name <name>
+      Sets the name of the column with the values. By default it is
+      "value".
+
+    --drop-empty
+      If set, empty values are not printed.
+
+    <id>
+      One or more identifier columns, selected as in 'tables help cols'.
+	`,
+}
+
+var meltValues string    // set the value columns, --values
+var meltVarName string   // set the name of the variable column, --var-name
+var meltValueName string // set the name of the value column, --value-name
+var meltDropEmpty bool   // drop empty values, --drop-empty
+
+func init() {
+	initCommonFlags(meltCmd)
+	meltCmd.Flag.StringVar(&meltValues, "values", "", "")
+	meltCmd.Flag.StringVar(&meltVarName, "var-name", "variable", "")
+	meltCmd.Flag.StringVar(&meltValueName, "value-name", "value", "")
+	meltCmd.Flag.BoolVar(&meltDropEmpty, "drop-empty", false, "")
+}
+
+func meltRun(c *cmdapp.Command, args []string) error {
+	r, err := openInput()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	w, err := openOutput()
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	header, err := r.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+	ids, vals, err := meltColumns(r, header, args)
+	if err != nil {
+		return err
+	}
+	cols := make([]string, 0, len(ids)+2)
+	for _, i := range ids {
+		cols = append(cols, header[i])
+	}
+	cols = append(cols, meltVarName, meltValueName)
+	if err := w.Write(cols); err != nil {
+		return err
+	}
+	for {
+		row, err := r.Read()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return err
+		}
+		if err := meltRow(w, header, row, ids, vals); err != nil {
+			return err
+		}
+	}
+	return w.Close()
+}
+
+// meltColumns returns the identifier and value columns of a table.
+func meltColumns(r recordReader, header, args []string) (ids, vals []int, err error) {
+	if meltVarName == meltValueName {
+		return nil, nil, fmt.Errorf("the variable and value columns have the same name %q", meltVarName)
+	}
+	sel := &columnSelector{r: r, header: header}
+	isID := make(map[int]bool)
+	for _, a := range args {
+		idx, err := sel.columns(a)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, j := range idx {
+			if j < 0 {
+				return nil, nil, fmt.Errorf("unknown column %q", a)
+			}
+			if header[j] == meltVarName || header[j] == meltValueName {
+				return nil, nil, fmt.Errorf("identifier column %q has the same name as a new column", header[j])
+			}
+			ids = append(ids, j)
+			isID[j] = true
+		}
+	}
+
+	if len(meltValues) == 0 {
+		for i := range header {
+			if !isID[i] {
+				vals = append(vals, i)
+			}
+		}
+		return ids, vals, nil
+	}
+	isVal := make(map[int]bool)
+	for _, a := range splitColumns(meltValues) {
+		idx, err := sel.columns(a)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, j := range idx {
+			if j < 0 {
+				return nil, nil, fmt.Errorf("unknown column %q", a)
+			}
+			if isID[j] || isVal[j] {
+				continue
+			}
+			vals = append(vals, j)
+			isVal[j] = true
+		}
+	}
+	return ids, vals, nil
+}
+
+// meltRow writes a row for each value column of a record.
+func meltRow(w rowWriter, header, rec []string, ids, vals []int) error {
+	for _, v := range vals {
+		val := cell(rec, v)
+		if meltDropEmpty && len(val) == 0 {
+			continue
+		}
+		row := make([]string, 0, len(ids)+2)
+		for _, i := range ids {
+			row = append(row, cell(rec, i))
+		}
+		row = append(row, header[v], val)
+		if err := w.Write(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
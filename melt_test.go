@@ -0,0 +1,68 @@
+// Copyright (c) 2016, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMelt(t *testing.T) {
+	header := []string{"Site", "Date", "sp1", "sp2"}
+	recs := [][]string{
+		[]string{"A", "2016-01-02", "2", ""},
+		[]string{"B", "2016-03-04", "1", "5"},
+	}
+	tests := []struct {
+		ids    []string
+		values string
+		drop   bool
+		rows   []string
+	}{
+		{[]string{"Site"}, "/^sp/", false, []string{"A sp1 2", "A sp2 ", "B sp1 1", "B sp2 5"}},
+		{[]string{"Site"}, "/^sp/", true, []string{"A sp1 2", "B sp1 1", "B sp2 5"}},
+		{[]string{"Site", "Date"}, "", true, []string{"A 2016-01-02 sp1 2", "B 2016-03-04 sp1 1", "B 2016-03-04 sp2 5"}},
+		{[]string{"#1"}, "Date..sp1", false, []string{"A Date 2016-01-02", "A sp1 2", "B Date 2016-03-04", "B sp1 1"}},
+		{[]string{"Site"}, "sp2,Site,sp1,/2$/", true, []string{"A sp1 2", "B sp2 5", "B sp1 1"}},
+		{[]string{"Site"}, "/^sp[0-9]{1,2}$/,Date", true, []string{"A sp1 2", "A Date 2016-01-02", "B sp1 1", "B sp2 5", "B Date 2016-03-04"}},
+	}
+	defer func() { meltValues, meltDropEmpty = "", false }()
+	for _, ts := range tests {
+		meltValues, meltDropEmpty = ts.values, ts.drop
+		ids, vals, err := meltColumns(&memReader{}, header, ts.ids)
+		if err != nil {
+			t.Errorf("Melt: %v: unexpected error: %v", ts.ids, err)
+			continue
+		}
+		w := &memWriter{}
+		for _, rec := range recs {
+			if err := meltRow(w, header, rec, ids, vals); err != nil {
+				t.Errorf("Melt: %v: unexpected error: %v", ts.ids, err)
+			}
+		}
+		var rows []string
+		for _, rec := range w.recs {
+			rows = append(rows, strings.Join(rec, " "))
+		}
+		if strings.Join(rows, "|") != strings.Join(ts.rows, "|") {
+			t.Errorf("Melt: %v: expecting %q, found %q", ts.ids, ts.rows, rows)
+		}
+	}
+
+	meltValues = ""
+	if _, _, err := meltColumns(&memReader{}, header, []string{"Place"}); err == nil {
+		t.Errorf("Melt: expecting error on unknown column")
+	}
+	meltValues = "sp1,Place"
+	if _, _, err := meltColumns(&memReader{}, header, []string{"Site"}); err == nil {
+		t.Errorf("Melt: expecting error on unknown value column")
+	}
+	meltValues = ""
+	meltVarName = "value"
+	defer func() { meltVarName = "variable" }()
+	if _, _, err := meltColumns(&memReader{}, header, []string{"Site"}); err == nil {
+		t.Errorf("Melt: expecting error on new columns with the same name")
+	}
+}
@@ -0,0 +1,493 @@
+// Copyright (c) 2016, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/js-arias/cmdapp"
+)
+
+var numberCmd = &cmdapp.Command{
+	Run: numberRun,
+	UsageLine: `number [-f <char>] [--dups <policy>] [--encoding <name>]
+	[--from <format>] [-i|--input <file>] [-n|--no-header]
+	[-o|--output <file>] [--ragged <policy>] [--schema <file>]
+	[--to <format>] [-g|--group <column>] [-p <number>] [<function>...]`,
+	Short: "adds row numbers, ranks, and cumulative columns",
+	Long: `
+Command number outputs the input table with new columns, at the end of the
+table, calculated with the values of the previous rows (or the following
+rows, or all the rows) of the table. A new column is defined as
+<name>=<function>, and if the name is not given, the function is used as
+the name. If no column is defined, a column with the row number, called
+Row, is added.
+
+The functions are:
+
+    index
+      The number of the row, starting at 1.
+
+    rank(<column>[,desc])
+      The rank of the row by the values of the column, with the same rank
+      for equal values, and skipping the following ranks (e.g. 1, 2, 2, 4).
+      The values are compared as numbers, and non numeric values are
+      smaller than numbers. With desc, the larger values are first. Rows
+      with an empty value have an empty rank.
+
+    dense_rank(<column>[,desc])
+      As rank, but without skipping ranks (e.g. 1, 2, 2, 3).
+
+    cumsum(<column>)
+      The sum of the numeric values of the column, up to the row.
+
+    cummean(<column>)
+      The mean of the numeric values of the column, up to the row.
+
+    lag(<column>[,<number>])
+      The value of the column in the previous row, or in the given number
+      of rows before.
+
+    lead(<column>[,<number>])
+      The value of the column in the next row, or in the given number of
+      rows after.
+
+    percent(<column>)
+      The numeric value of the column, as the percent of the sum of all
+      the values of the column.
+
+With -g or --group, the functions are calculated independently for each
+value of the group column, i.e. the row numbers, and the cumulative values,
+restart for each group, and the previous, and next rows, are the rows of
+the same group. The rows are printed in the order of the input table.
+
+The functions index, cumsum, cummean, and lag, are calculated while the
+table is read, and lead keeps only the required rows, but rank,
+dense_rank, and percent, require all the rows of the table in memory.
+As the rows are printed in the input order, with --group, a row waiting
+for a lead value keeps all the following rows, so if the group of the
+row has no more rows, all the rows after it are kept until the end of
+the table.
+
+Because functions use parenthesis, they must be enclosed in single quotes
+(') to protect them from being interpreted by the shell.
+
+Options are:
+
+    -f <char>
+      Sets the field separation character. By default the value is the tab
+      character.
+
+    --dups <policy>
+      Sets how to handle duplicated column names in the header, one of
+      warn (the default), error, or rename. See 'tables help formats'.
+
+    --encoding <name>
+      Sets the character encoding of the input. By default the encoding
+      is detected from the input. See 'tables help formats' for the
+      available encodings.
+
+    --from <format>
+      Sets the format of the input table. By default the format is
+      detected from the extension of the input file, or it is a delimited
+      text table. See 'tables help formats' for the available formats.
+
+    -i <file>
+    --input <file>
+      Read the table from <file> instead of stdin.
+
+    -n
+    --no-header
+      If set, the table will be printed without a header.
+
+    -o <file>
+    --output <file>
+      Write the resulting table to <file> instead of stdout.
+
+    --ragged <policy>
+      Sets how to handle records with a different number of fields than
+      the header, one of error (the default), pad, truncate, or skip. See
+      'tables help formats'.
+
+    --schema <file>
+      Read the types of the columns of the input from a schema file. See
+      'tables help schema'.
+
+    --to <format>
+      Sets the format of the output table. By default the format is
+      detected from the extension of the output file, or it is a delimited
+      text table. See 'tables help formats' for the available formats.
+
+    -g <column>
+    --group <column>
+      Calculates the functions for each value of the given column.
+
+    -p <number>
+      Sets the number of decimals of the values of cumsum, cummean, and
+      percent. The default is 2.
+
+    <function>
+      One or more new columns.
+	`,
+}
+
+var numberGroup string // set the group column, -g|--group
+var numberPrec int     // set the decimals, -p
+
+func init() {
+	initCommonFlags(numberCmd)
+	numberCmd.Flag.StringVar(&numberGroup, "group", "", "")
+	numberCmd.Flag.StringVar(&numberGroup, "g", "", "")
+	numberCmd.Flag.IntVar(&numberPrec, "p", 2, "")
+}
+
+func numberRun(c *cmdapp.Command, args []string) error {
+	if len(args) == 0 {
+		args = []string{"Row=index"}
+	}
+	r, err := openInput()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	w, err := openOutput()
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	header, err := r.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+	nm, err := newNumberer(header, args)
+	if err != nil {
+		return err
+	}
+	cols := append([]string{}, header...)
+	for _, f := range nm.fns {
+		cols = append(cols, f.name)
+	}
+	if err := w.Write(cols); err != nil {
+		return err
+	}
+	for {
+		row, err := r.Read()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return err
+		}
+		if err := nm.add(row, w); err != nil {
+			return err
+		}
+	}
+	if err := nm.flush(w); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// numberFunc is a function of a new column.
+type numberFunc struct {
+	name string // name of the new column
+	fn   string
+	col  int  // column used by the function
+	n    int  // offset of lag and lead
+	desc bool // descending rank
+}
+
+// whole returns true if the function requires all the rows of the table.
+func (f *numberFunc) whole() bool {
+	switch f.fn {
+	case "rank", "dense_rank", "percent":
+		return true
+	}
+	return false
+}
+
+// parseNumberFunc returns a function from a definition.
+func parseNumberFunc(header []string, def string) (*numberFunc, error) {
+	f := &numberFunc{name: def, fn: def, col: -1}
+	if i := strings.Index(def, "="); i >= 0 {
+		f.name, f.fn = def[:i], def[i+1:]
+	}
+	if len(f.name) == 0 {
+		return nil, fmt.Errorf("invalid function %q: empty column name", def)
+	}
+	var args []string
+	if i := strings.Index(f.fn, "("); i >= 0 {
+		if !strings.HasSuffix(f.fn, ")") {
+			return nil, fmt.Errorf("invalid function %q: expecting ')'", def)
+		}
+		arg := f.fn[i+1 : len(f.fn)-1]
+		f.fn = f.fn[:i]
+		args = []string{arg}
+		if j := strings.LastIndex(arg, ","); j >= 0 {
+			args = []string{arg[:j], strings.TrimSpace(arg[j+1:])}
+		}
+	}
+
+	switch f.fn {
+	case "index":
+		if len(args) > 0 {
+			return nil, fmt.Errorf("invalid function %q: index without arguments", def)
+		}
+		return f, nil
+	case "rank", "dense_rank":
+		if len(args) == 2 {
+			switch args[1] {
+			case "desc":
+				f.desc = true
+			case "asc":
+			default:
+				return nil, fmt.Errorf("invalid function %q: unknown order %q", def, args[1])
+			}
+		}
+	case "lag", "lead":
+		f.n = 1
+		if len(args) == 2 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid function %q: invalid number of rows %q", def, args[1])
+			}
+			f.n = n
+		}
+	case "cumsum", "cummean", "percent":
+		if len(args) == 2 {
+			// the comma is part of the column name
+			args = []string{args[0] + "," + args[1]}
+		}
+	default:
+		return nil, fmt.Errorf("invalid function %q: unknown function %q", def, f.fn)
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("invalid function %q: expecting a column", def)
+	}
+	col, err := findColumn(header, args[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid function %q: %v", def, err)
+	}
+	if col < 0 {
+		return nil, fmt.Errorf("invalid function %q: unknown column %q", def, args[0])
+	}
+	f.col = col
+	return f, nil
+}
+
+// numberRow is a row waiting to be written.
+type numberRow struct {
+	rec  []string
+	vals []string // values of the new columns
+	wait int      // number of lead values not yet known
+}
+
+// numberGroupState stores the state of the functions in a group.
+type numberGroupState struct {
+	rows int
+	sum  []float64      // sum of the values of each function
+	n    []int          // number of numeric values of each function
+	prev [][]string     // last values of each lag function
+	next [][]*numberRow // rows waiting for each lead function
+	all  []*numberRow   // all the rows, if a function requires them
+}
+
+// numberer adds the columns defined by functions to the rows of a table.
+type numberer struct {
+	fns    []*numberFunc
+	group  int  // group column, -1 if not used
+	whole  bool // keep all the rows
+	groups map[string]*numberGroupState
+	order  []string     // groups in order
+	queue  []*numberRow // rows not yet written
+}
+
+// newNumberer returns a numberer for a table with a given header.
+func newNumberer(header, defs []string) (*numberer, error) {
+	nm := &numberer{
+		group:  -1,
+		groups: make(map[string]*numberGroupState),
+	}
+	names := append([]string{}, header...)
+	for _, d := range defs {
+		f, err := parseNumberFunc(header, d)
+		if err != nil {
+			return nil, err
+		}
+		if hasColumn(names, f.name) {
+			return nil, fmt.Errorf("invalid function %q: column %q already in the table", d, f.name)
+		}
+		names = append(names, f.name)
+		nm.fns = append(nm.fns, f)
+		if f.whole() {
+			nm.whole = true
+		}
+	}
+	if len(numberGroup) > 0 {
+		g, err := findColumn(header, numberGroup)
+		if err != nil {
+			return nil, err
+		}
+		if g < 0 {
+			return nil, fmt.Errorf("unknown column %q", numberGroup)
+		}
+		nm.group = g
+	}
+	return nm, nil
+}
+
+// add adds a row of the table, and writes the rows that are complete.
+func (nm *numberer) add(rec []string, w rowWriter) error {
+	g := ""
+	if nm.group >= 0 {
+		g = cell(rec, nm.group)
+	}
+	st, ok := nm.groups[g]
+	if !ok {
+		st = &numberGroupState{
+			sum:  make([]float64, len(nm.fns)),
+			n:    make([]int, len(nm.fns)),
+			prev: make([][]string, len(nm.fns)),
+			next: make([][]*numberRow, len(nm.fns)),
+		}
+		nm.groups[g] = st
+		nm.order = append(nm.order, g)
+	}
+	st.rows++
+	row := &numberRow{rec: rec, vals: make([]string, len(nm.fns))}
+	if nm.whole {
+		st.all = append(st.all, row)
+	}
+	for i, f := range nm.fns {
+		v := ""
+		if f.col >= 0 {
+			v = cell(rec, f.col)
+		}
+		x, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		isNum := len(v) > 0 && err == nil
+		switch f.fn {
+		case "index":
+			row.vals[i] = strconv.Itoa(st.rows)
+		case "cumsum":
+			if isNum {
+				st.sum[i] += x
+			}
+			row.vals[i] = strconv.FormatFloat(st.sum[i], 'f', numberPrec, 64)
+		case "cummean":
+			if isNum {
+				st.sum[i] += x
+				st.n[i]++
+			}
+			if st.n[i] > 0 {
+				row.vals[i] = strconv.FormatFloat(st.sum[i]/float64(st.n[i]), 'f', numberPrec, 64)
+			}
+		case "percent":
+			if isNum {
+				st.sum[i] += x
+			}
+		case "lag":
+			if len(st.prev[i]) == f.n {
+				row.vals[i] = st.prev[i][0]
+				st.prev[i] = st.prev[i][1:]
+			}
+			st.prev[i] = append(st.prev[i], v)
+		case "lead":
+			if len(st.next[i]) == f.n {
+				p := st.next[i][0]
+				p.vals[i] = v
+				p.wait--
+				st.next[i] = st.next[i][1:]
+			}
+			st.next[i] = append(st.next[i], row)
+			row.wait++
+		}
+	}
+	nm.queue = append(nm.queue, row)
+	if nm.whole {
+		return nil
+	}
+	for len(nm.queue) > 0 && nm.queue[0].wait == 0 {
+		if err := nm.write(nm.queue[0], w); err != nil {
+			return err
+		}
+		nm.queue = nm.queue[1:]
+	}
+	return nil
+}
+
+// flush calculates the functions that require all the rows, and writes
+// the remaining rows.
+func (nm *numberer) flush(w rowWriter) error {
+	for i, f := range nm.fns {
+		if !f.whole() {
+			continue
+		}
+		for _, g := range nm.order {
+			st := nm.groups[g]
+			if f.fn == "percent" {
+				for _, row := range st.all {
+					x, err := strconv.ParseFloat(strings.TrimSpace(cell(row.rec, f.col)), 64)
+					if err != nil || st.sum[i] == 0 {
+						continue
+					}
+					row.vals[i] = strconv.FormatFloat(100*x/st.sum[i], 'f', numberPrec, 64)
+				}
+				continue
+			}
+			rankRows(st.all, i, f)
+		}
+	}
+	for _, row := range nm.queue {
+		if err := nm.write(row, w); err != nil {
+			return err
+		}
+	}
+	nm.queue = nil
+	return nil
+}
+
+// rankRows sets the rank of a set of rows, for the function i.
+func rankRows(rows []*numberRow, i int, f *numberFunc) {
+	var sorted []*numberRow
+	for _, row := range rows {
+		if len(cell(row.rec, f.col)) > 0 {
+			sorted = append(sorted, row)
+		}
+	}
+	less := func(a, b string) bool {
+		if f.desc {
+			a, b = b, a
+		}
+		return compare(getFieldValue(a), getFieldValue(b), opLess)
+	}
+	sort.SliceStable(sorted, func(a, b int) bool {
+		return less(cell(sorted[a].rec, f.col), cell(sorted[b].rec, f.col))
+	})
+	rank, dense := 0, 0
+	for j, row := range sorted {
+		v := cell(row.rec, f.col)
+		if j == 0 || less(cell(sorted[j-1].rec, f.col), v) {
+			rank = j + 1
+			dense++
+		}
+		if f.fn == "dense_rank" {
+			row.vals[i] = strconv.Itoa(dense)
+			continue
+		}
+		row.vals[i] = strconv.Itoa(rank)
+	}
+}
+
+// write writes a row with the values of the new columns.
+func (nm *numberer) write(row *numberRow, w rowWriter) error {
+	return w.Write(append(append([]string{}, row.rec...), row.vals...))
+}
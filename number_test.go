@@ -0,0 +1,97 @@
+// Copyright (c) 2016, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNumber(t *testing.T) {
+	header := []string{"G", "V"}
+	recs := [][]string{
+		[]string{"a", "3"},
+		[]string{"b", "5"},
+		[]string{"a", "1"},
+		[]string{"a", "3"},
+		[]string{"b", "x"},
+	}
+	tests := []struct {
+		group string
+		defs  []string
+		cols  []string // values of the new columns of each row
+	}{
+		{"", []string{"index", "S=cumsum(V)", "M=cummean(V)", "L=lag(V)", "N=lead(V,2)"}, []string{
+			"1 3.00 3.00  1",
+			"2 8.00 4.00 3 3",
+			"3 9.00 3.00 5 x",
+			"4 12.00 3.00 1 ",
+			"5 12.00 3.00 3 ",
+		}},
+		{"", []string{"R=rank(V)", "D=dense_rank(V,desc)", "P=percent(V)"}, []string{
+			"3 2 25.00",
+			"5 1 41.67",
+			"2 3 8.33",
+			"3 2 25.00",
+			"1 4 ",
+		}},
+		{"G", []string{"index", "S=cumsum(V)", "N=lead(V)", "L=lag(V)", "R=rank(V)"}, []string{
+			"1 3.00 1  2",
+			"1 5.00 x  2",
+			"2 4.00 3 3 1",
+			"3 7.00  1 2",
+			"2 5.00  5 1",
+		}},
+	}
+	defer func() { numberGroup = "" }()
+	for _, ts := range tests {
+		numberGroup = ts.group
+		nm, err := newNumberer(header, ts.defs)
+		if err != nil {
+			t.Errorf("Number: %v: unexpected error: %v", ts.defs, err)
+			continue
+		}
+		w := &memWriter{}
+		for _, rec := range recs {
+			if err := nm.add(rec, w); err != nil {
+				t.Errorf("Number: %v: unexpected error: %v", ts.defs, err)
+			}
+		}
+		if err := nm.flush(w); err != nil {
+			t.Errorf("Number: %v: unexpected error: %v", ts.defs, err)
+		}
+		if len(w.recs) != len(recs) {
+			t.Errorf("Number: %v: expecting %d rows, found %d", ts.defs, len(recs), len(w.recs))
+			continue
+		}
+		for i, rec := range w.recs {
+			if rec[0] != recs[i][0] || rec[1] != recs[i][1] {
+				t.Errorf("Number: %v: row %d out of order", ts.defs, i)
+			}
+			if s := strings.Join(rec[2:], " "); s != ts.cols[i] {
+				t.Errorf("Number: %v: row %d: expecting %q, found %q", ts.defs, i, ts.cols[i], s)
+			}
+		}
+	}
+
+	numberGroup = ""
+	nm, err := newNumberer(header, []string{"cumsum(V)"})
+	if err != nil {
+		t.Fatalf("Number: unexpected error: %v", err)
+	}
+	w := &memWriter{}
+	nm.add([]string{"a", "1000000"}, w)
+	nm.add([]string{"a", "0.1"}, w)
+	nm.add([]string{"a", "0.2"}, w)
+	if v := w.recs[2][2]; v != "1000000.30" {
+		t.Errorf("Number: cumsum: expecting %q, found %q", "1000000.30", v)
+	}
+
+	for _, d := range []string{"V=index", "sum(V)", "lag(V,0)", "rank(V,up)", "cumsum(W)", "lead(V", "=index", "index(V)"} {
+		if _, err := newNumberer(header, []string{d}); err == nil {
+			t.Errorf("Number: %q: expecting error", d)
+		}
+	}
+}
@@ -0,0 +1,375 @@
+// Copyright (c) 2016, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// OpenDocument name spaces.
+const (
+	odsOfficeNS = "urn:oasis:names:tc:opendocument:xmlns:office:1.0"
+	odsTableNS  = "urn:oasis:names:tc:opendocument:xmlns:table:1.0"
+	odsTextNS   = "urn:oasis:names:tc:opendocument:xmlns:text:1.0"
+)
+
+// odsMimeType is the mime type of ODS files.
+const odsMimeType = "application/vnd.oasis.opendocument.spreadsheet"
+
+// newODSReader returns a reader for OpenDocument spreadsheets. The
+// parameter is the name or the index (starting at 1) of the sheet, by
+// default the first sheet is read.
+func newODSReader(in io.Reader, param string) (recordReader, error) {
+	z, err := readZip(in)
+	if err != nil {
+		return nil, err
+	}
+	var content *zip.File
+	for _, f := range z.File {
+		if f.Name == "content.xml" {
+			content = f
+			break
+		}
+	}
+	if content == nil {
+		return nil, fmt.Errorf("ods: file content.xml not found")
+	}
+	rc, err := content.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	dec := xml.NewDecoder(rc)
+	sheet := 0
+	found := false
+	for !found {
+		tok, err := dec.Token()
+		if err != nil {
+			if err == io.EOF {
+				return nil, fmt.Errorf("ods: sheet %s not found", param)
+			}
+			return nil, err
+		}
+		st, ok := tok.(xml.StartElement)
+		if !ok || st.Name.Space != odsTableNS || st.Name.Local != "table" {
+			continue
+		}
+		sheet++
+		switch {
+		case len(param) == 0:
+			found = true
+		case odsAttr(st, odsTableNS, "name") == param:
+			found = true
+		case param == strconv.Itoa(sheet):
+			found = true
+		default:
+			if err := dec.Skip(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	r := &memReader{}
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		if end, ok := tok.(xml.EndElement); ok && end.Name.Space == odsTableNS && end.Name.Local == "table" {
+			break
+		}
+		st, ok := tok.(xml.StartElement)
+		if !ok || st.Name.Space != odsTableNS || st.Name.Local != "table-row" {
+			continue
+		}
+		rec, err := odsRow(dec)
+		if err != nil {
+			return nil, err
+		}
+		if len(rec) == 0 {
+			// empty rows are ignored, so repeated empty rows
+			// are not expanded
+			continue
+		}
+		n := odsRepeat(st, "number-rows-repeated")
+		for i := 0; i < n; i++ {
+			r.recs = append(r.recs, append([]string{}, rec...))
+		}
+	}
+	padRecords(r.recs)
+	return r, nil
+}
+
+// odsAttr returns the value of an attribute.
+func odsAttr(st xml.StartElement, space, local string) string {
+	for _, a := range st.Attr {
+		if a.Name.Space == space && a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// odsRepeat returns the value of a repetition attribute.
+func odsRepeat(st xml.StartElement, attr string) int {
+	n, err := strconv.Atoi(odsAttr(st, odsTableNS, attr))
+	if err != nil || n < 1 {
+		return 1
+	}
+	return n
+}
+
+// odsRow reads the cells of a table row. Trailing empty cells are not
+// included.
+func odsRow(dec *xml.Decoder) ([]string, error) {
+	var rec []string
+	empty := 0 // pending empty cells
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := tok.(xml.EndElement); ok {
+			return rec, nil
+		}
+		st, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if st.Name.Space != odsTableNS || (st.Name.Local != "table-cell" && st.Name.Local != "covered-table-cell") {
+			if err := dec.Skip(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		v, err := odsCell(dec, st)
+		if err != nil {
+			return nil, err
+		}
+		n := odsRepeat(st, "number-columns-repeated")
+		if len(v) == 0 {
+			empty += n
+			continue
+		}
+		for ; empty > 0; empty-- {
+			rec = append(rec, "")
+		}
+		for i := 0; i < n; i++ {
+			rec = append(rec, v)
+		}
+	}
+}
+
+// odsCell returns the value of a cell.
+func odsCell(dec *xml.Decoder, st xml.StartElement) (string, error) {
+	var b strings.Builder
+	paras := 0
+	space := false // last character written is a white space
+	for depth := 1; depth > 0; {
+		tok, err := dec.Token()
+		if err != nil {
+			return "", err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Space == odsOfficeNS && t.Name.Local == "annotation" {
+				if err := dec.Skip(); err != nil {
+					return "", err
+				}
+				continue
+			}
+			depth++
+			if t.Name.Space != odsTextNS {
+				continue
+			}
+			switch t.Name.Local {
+			case "p", "h":
+				if paras > 0 {
+					b.WriteByte('\n')
+				}
+				paras++
+				space = false
+			case "s":
+				n, err := strconv.Atoi(odsAttr(t, odsTextNS, "c"))
+				if err != nil || n < 1 {
+					n = 1
+				}
+				b.WriteString(strings.Repeat(" ", n))
+				space = true
+			case "tab":
+				b.WriteByte('\t')
+			case "line-break":
+				b.WriteByte('\n')
+			}
+		case xml.EndElement:
+			depth--
+		case xml.CharData:
+			// white space is collapsed as defined by ODF
+			for _, c := range string(t) {
+				if c == ' ' || c == '\t' || c == '\n' || c == '\r' {
+					if !space {
+						b.WriteByte(' ')
+					}
+					space = true
+					continue
+				}
+				b.WriteRune(c)
+				space = false
+			}
+		}
+	}
+
+	switch odsAttr(st, odsOfficeNS, "value-type") {
+	case "float", "percentage", "currency":
+		return odsAttr(st, odsOfficeNS, "value"), nil
+	case "date":
+		return odsAttr(st, odsOfficeNS, "date-value"), nil
+	case "time":
+		return odsAttr(st, odsOfficeNS, "time-value"), nil
+	case "boolean":
+		return odsAttr(st, odsOfficeNS, "boolean-value"), nil
+	}
+	return b.String(), nil
+}
+
+// odsWriter writes a table as an OpenDocument spreadsheet. As the
+// spreadsheet is a zip file, the table is written when Flush is called.
+type odsWriter struct {
+	out   io.Writer
+	head  bool // the first record is the header
+	sheet string
+	recs  [][]string
+}
+
+// newODSWriter returns a writer for OpenDocument spreadsheets. The
+// parameter is the name of the sheet.
+func newODSWriter(out io.Writer, param string) recordWriter {
+	if len(param) == 0 {
+		param = "Sheet1"
+	}
+	return &odsWriter{out: out, head: !noHead, sheet: param}
+}
+
+func (w *odsWriter) Write(record []string) error {
+	w.recs = append(w.recs, append([]string{}, record...))
+	return nil
+}
+
+// odsManifest is the manifest of an ODS file.
+const odsManifest = `<manifest:manifest xmlns:manifest="urn:oasis:names:tc:opendocument:xmlns:manifest:1.0" manifest:version="1.2">
+<manifest:file-entry manifest:full-path="/" manifest:version="1.2" manifest:media-type="` + odsMimeType + `"/>
+<manifest:file-entry manifest:full-path="content.xml" manifest:media-type="text/xml"/>
+</manifest:manifest>`
+
+func (w *odsWriter) Flush() error {
+	z := zip.NewWriter(w.out)
+
+	// the mime type must be the first file, and it must be
+	// uncompressed
+	fw, err := z.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	if err != nil {
+		return err
+	}
+	io.WriteString(fw, odsMimeType)
+	fw, err = z.Create("META-INF/manifest.xml")
+	if err != nil {
+		return err
+	}
+	io.WriteString(fw, xml.Header+odsManifest)
+
+	fw, err = z.Create("content.xml")
+	if err != nil {
+		return err
+	}
+	var b bytes.Buffer
+	b.WriteString(xml.Header)
+	b.WriteString(`<office:document-content xmlns:office="` + odsOfficeNS + `" xmlns:table="` + odsTableNS + `" xmlns:text="` + odsTextNS + `" office:version="1.2"><office:body><office:spreadsheet><table:table table:name="`)
+	xml.EscapeText(&b, []byte(w.sheet))
+	b.WriteString(`">`)
+	for i, rec := range w.recs {
+		b.WriteString("<table:table-row>")
+		empty := 0
+		for _, f := range rec {
+			if len(f) == 0 {
+				empty++
+				continue
+			}
+			odsEmptyCells(&b, empty)
+			empty = 0
+			if v, ok := getFieldValue(f).(float64); ok && (i > 0 || !w.head) && !math.IsInf(v, 0) && !math.IsNaN(v) {
+				fmt.Fprintf(&b, `<table:table-cell office:value-type="float" office:value="%s">`, strconv.FormatFloat(v, 'g', -1, 64))
+			} else {
+				b.WriteString(`<table:table-cell office:value-type="string">`)
+			}
+			odsText(&b, f)
+			b.WriteString("</table:table-cell>")
+		}
+		odsEmptyCells(&b, empty)
+		b.WriteString("</table:table-row>")
+		if _, err := fw.Write(b.Bytes()); err != nil {
+			return err
+		}
+		b.Reset()
+	}
+	b.WriteString(`</table:table></office:spreadsheet></office:body></office:document-content>`)
+	if _, err := fw.Write(b.Bytes()); err != nil {
+		return err
+	}
+	return z.Close()
+}
+
+// odsEmptyCells writes n empty cells.
+func odsEmptyCells(b *bytes.Buffer, n int) {
+	switch {
+	case n == 1:
+		b.WriteString("<table:table-cell/>")
+	case n > 1:
+		fmt.Fprintf(b, `<table:table-cell table:number-columns-repeated="%d"/>`, n)
+	}
+}
+
+// odsText writes the text of a cell, as one or more paragraphs, keeping
+// the white spaces.
+func odsText(b *bytes.Buffer, f string) {
+	for _, p := range strings.Split(f, "\n") {
+		b.WriteString("<text:p>")
+		rs := []rune(p)
+		for i := 0; i < len(rs); i++ {
+			switch rs[i] {
+			case '\t':
+				b.WriteString("<text:tab/>")
+			case ' ':
+				j := i
+				for j < len(rs) && rs[j] == ' ' {
+					j++
+				}
+				n := j - i
+				if i > 0 && j < len(rs) {
+					// a single space between words is
+					// kept as text
+					b.WriteByte(' ')
+					n--
+				}
+				if n == 1 {
+					b.WriteString("<text:s/>")
+				} else if n > 1 {
+					fmt.Fprintf(b, `<text:s text:c="%d"/>`, n)
+				}
+				i = j - 1
+			default:
+				xml.EscapeText(b, []byte(string(rs[i])))
+			}
+		}
+		b.WriteString("</text:p>")
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright (c) 2016, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"testing"
+)
+
+func testODSRead(t *testing.T, r recordReader, rs [][]string) {
+	for i := 0; ; i++ {
+		row, err := r.Read()
+		if err != nil {
+			if err == io.EOF {
+				if i != len(rs) {
+					t.Errorf("ODS: expecting %d records, found %d", len(rs), i)
+				}
+				break
+			}
+			t.Errorf("ODS: unexpected error: %v", err)
+			break
+		}
+		if i >= len(rs) {
+			continue
+		}
+		if len(row) != len(rs[i]) {
+			t.Errorf("ODS: expecting %d fields, found %d (row %d)", len(rs[i]), len(row), i)
+			continue
+		}
+		for j, v := range rs[i] {
+			if row[j] != v {
+				t.Errorf("ODS: expecting %q in row %d col %d, found %q", v, i, j, row[j])
+			}
+		}
+	}
+}
+
+func TestODS(t *testing.T) {
+	rs := [][]string{
+		[]string{"Item", "Cost", "", "Description"},
+		[]string{"1", "50.5", "", "  rubber  <gloves> "},
+		[]string{"2", "", "", "test\ttubes\nand more"},
+	}
+	var b bytes.Buffer
+	w := &odsWriter{out: &b, head: true, sheet: "Data"}
+	for _, r := range rs {
+		w.Write(r)
+	}
+	if err := w.Flush(); err != nil {
+		t.Errorf("ODS: unexpected error: %v", err)
+	}
+	for _, p := range []string{"", "Data", "1"} {
+		r, err := newODSReader(bytes.NewReader(b.Bytes()), p)
+		if err != nil {
+			t.Errorf("ODS: unexpected error: %v", err)
+			continue
+		}
+		testODSRead(t, r, rs)
+	}
+	if _, err := newODSReader(bytes.NewReader(b.Bytes()), "Other"); err == nil {
+		t.Errorf("ODS: expecting error on an undefined sheet")
+	}
+}
+
+var odsContent = `<?xml version="1.0" encoding="UTF-8"?>
+<office:document-content xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0" xmlns:table="urn:oasis:names:tc:opendocument:xmlns:table:1.0" xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0">
+<office:body><office:spreadsheet>
+<table:table table:name="First"><table:table-row><table:table-cell><text:p>x</text:p></table:table-cell></table:table-row></table:table>
+<table:table table:name="Second">
+<table:table-row>
+<table:table-cell office:value-type="string"><text:p>A</text:p></table:table-cell>
+<table:table-cell table:number-columns-repeated="2"/>
+<table:table-cell office:value-type="string"><text:p>D</text:p></table:table-cell>
+<table:table-cell table:number-columns-repeated="1020"/>
+</table:table-row>
+<table:table-row table:number-rows-repeated="2">
+<table:table-cell office:value-type="float" office:value="0.25" table:number-columns-repeated="2"><text:p>25%</text:p></table:table-cell>
+<table:table-cell office:value-type="boolean" office:boolean-value="true"><text:p>TRUE</text:p><office:annotation><text:p>note</text:p></office:annotation></table:table-cell>
+</table:table-row>
+<table:table-row table:number-rows-repeated="1048570"><table:table-cell table:number-columns-repeated="1024"/></table:table-row>
+</table:table>
+</office:spreadsheet></office:body></office:document-content>`
+
+func TestODSRepeated(t *testing.T) {
+	var b bytes.Buffer
+	z := zip.NewWriter(&b)
+	fw, _ := z.Create("content.xml")
+	fw.Write([]byte(odsContent))
+	z.Close()
+
+	r, err := newODSReader(bytes.NewReader(b.Bytes()), "2")
+	if err != nil {
+		t.Errorf("ODS: unexpected error: %v", err)
+		return
+	}
+	rs := [][]string{
+		[]string{"A", "", "", "D"},
+		[]string{"0.25", "0.25", "true", ""},
+		[]string{"0.25", "0.25", "true", ""},
+	}
+	testODSRead(t, r, rs)
+}
@@ -0,0 +1,666 @@
+// Copyright (c) 2016, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"time"
+)
+
+// parquetMagic is the magic number at the start and end of Parquet files.
+const parquetMagic = "PAR1"
+
+// Parquet physical types.
+const (
+	pqBoolean   = 0
+	pqInt32     = 1
+	pqInt64     = 2
+	pqInt96     = 3
+	pqFloat     = 4
+	pqDouble    = 5
+	pqByteArray = 6
+	pqFixed     = 7
+)
+
+// Parquet encodings.
+const (
+	pqPlain          = 0
+	pqPlainDict      = 2
+	pqRLE            = 3
+	pqRLEDictionary  = 8
+	pqDataPage       = 0
+	pqDictionaryPage = 2
+	pqDataPageV2     = 3
+)
+
+// Parquet converted types used by the reader.
+const (
+	pqConvUTF8            = 0
+	pqConvDate            = 6
+	pqConvTimestampMillis = 9
+	pqConvTimestampMicros = 10
+)
+
+// newParquetWriter returns a writer for Parquet files. The file has a
+// single row group, and each column is written as a single uncompressed
+// data page.
+func newParquetWriter(out io.Writer, param string) recordWriter {
+	return &columnarWriter{out: out, write: writeParquet}
+}
+
+// countWriter is a writer that counts the number of bytes written.
+type countWriter struct {
+	w *bufio.Writer
+	n int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// parquetKinds are the physical types of each column kind.
+var parquetKinds = []int32{
+	intKind:    pqInt64,
+	floatKind:  pqDouble,
+	stringKind: pqByteArray,
+}
+
+// writeParquet writes a table as a Parquet file.
+func writeParquet(out io.Writer, header []string, kinds []int, recs [][]string) error {
+	w := &countWriter{w: bufio.NewWriter(out)}
+	io.WriteString(w, parquetMagic)
+	type chunk struct {
+		offset, size int64
+	}
+	chunks := make([]chunk, len(header))
+	for i := range header {
+		var levels, values bytes.Buffer
+		defs := make([]int, len(recs))
+		var buf [8]byte
+		for j, rec := range recs {
+			f := cell(rec, i)
+			if len(f) == 0 {
+				continue
+			}
+			defs[j] = 1
+			switch kinds[i] {
+			case intKind:
+				v, _ := strconv.ParseInt(f, 10, 64)
+				binary.LittleEndian.PutUint64(buf[:], uint64(v))
+				values.Write(buf[:])
+			case floatKind:
+				v, _ := strconv.ParseFloat(f, 64)
+				binary.LittleEndian.PutUint64(buf[:], math.Float64bits(v))
+				values.Write(buf[:])
+			default:
+				binary.LittleEndian.PutUint32(buf[:4], uint32(len(f)))
+				values.Write(buf[:4])
+				values.WriteString(f)
+			}
+		}
+		rle := encodeRLE(defs)
+		binary.LittleEndian.PutUint32(buf[:4], uint32(len(rle)))
+		levels.Write(buf[:4])
+		levels.Write(rle)
+		size := int32(levels.Len() + values.Len())
+
+		var ph thriftWriter
+		ph.begin()
+		ph.i32(1, pqDataPage)
+		ph.i32(2, size)
+		ph.i32(3, size)
+		ph.structField(5)
+		ph.i32(1, int32(len(recs)))
+		ph.i32(2, pqPlain)
+		ph.i32(3, pqRLE)
+		ph.i32(4, pqRLE)
+		ph.end()
+		ph.end()
+
+		chunks[i].offset = w.n
+		w.Write(ph.b)
+		w.Write(levels.Bytes())
+		w.Write(values.Bytes())
+		chunks[i].size = w.n - chunks[i].offset
+	}
+
+	var md thriftWriter
+	md.begin()
+	md.i32(1, 1)
+	md.list(2, thriftStruct, len(header)+1)
+	md.begin()
+	md.binary(4, []byte("schema"))
+	md.i32(5, int32(len(header)))
+	md.end()
+	var total int64
+	for i, h := range header {
+		md.begin()
+		md.i32(1, parquetKinds[kinds[i]])
+		md.i32(3, 1) // optional
+		md.binary(4, []byte(h))
+		if kinds[i] == stringKind {
+			md.i32(6, pqConvUTF8)
+			md.structField(10)
+			md.structField(1) // string logical type
+			md.end()
+			md.end()
+		}
+		md.end()
+		total += chunks[i].size
+	}
+	md.i64(3, int64(len(recs)))
+	md.list(4, thriftStruct, 1)
+	md.begin()
+	md.list(1, thriftStruct, len(header))
+	for i, h := range header {
+		md.begin()
+		md.i64(2, chunks[i].offset)
+		md.structField(3)
+		md.i32(1, parquetKinds[kinds[i]])
+		md.list(2, thriftI32, 2)
+		md.zigzag(pqPlain)
+		md.zigzag(pqRLE)
+		md.list(3, thriftBinary, 1)
+		md.bytes([]byte(h))
+		md.i32(4, 0) // uncompressed
+		md.i64(5, int64(len(recs)))
+		md.i64(6, chunks[i].size)
+		md.i64(7, chunks[i].size)
+		md.i64(9, chunks[i].offset)
+		md.end()
+		md.end()
+	}
+	md.i64(2, total)
+	md.i64(3, int64(len(recs)))
+	md.end()
+	md.binary(6, []byte("tables"))
+	md.end()
+
+	w.Write(md.b)
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], uint32(len(md.b)))
+	w.Write(buf[:])
+	io.WriteString(w, parquetMagic)
+	return w.w.Flush()
+}
+
+// encodeRLE encodes definition levels of bit width 1 using runs of the RLE
+// and bit-packing hybrid encoding.
+func encodeRLE(levels []int) []byte {
+	var b []byte
+	var buf [binary.MaxVarintLen64]byte
+	for i := 0; i < len(levels); {
+		j := i
+		for j < len(levels) && levels[j] == levels[i] {
+			j++
+		}
+		n := binary.PutUvarint(buf[:], uint64(j-i)<<1)
+		b = append(b, buf[:n]...)
+		b = append(b, byte(levels[i]))
+		i = j
+	}
+	return b
+}
+
+// decodeHybrid decodes n values encoded with the RLE and bit-packing hybrid
+// encoding.
+func decodeHybrid(data []byte, width, n int) ([]int, error) {
+	if width > 32 {
+		return nil, fmt.Errorf("parquet: invalid bit width %d", width)
+	}
+	vals := make([]int, 0, n)
+	r := bytes.NewReader(data)
+	for len(vals) < n {
+		h, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, errors.New("parquet: truncated levels")
+		}
+		if h&1 == 0 {
+			// RLE run
+			var v int
+			for i := 0; i < (width+7)/8; i++ {
+				c, err := r.ReadByte()
+				if err != nil {
+					return nil, errors.New("parquet: truncated levels")
+				}
+				v |= int(c) << (8 * uint(i))
+			}
+			for k := uint64(0); k < h>>1 && len(vals) < n; k++ {
+				vals = append(vals, v)
+			}
+			continue
+		}
+		// bit-packed groups of 8 values
+		if h>>1 > uint64(r.Len()) {
+			return nil, errors.New("parquet: truncated levels")
+		}
+		bits := make([]byte, int(h>>1)*width)
+		if _, err := io.ReadFull(r, bits); err != nil {
+			return nil, errors.New("parquet: truncated levels")
+		}
+		for k := 0; k < int(h>>1)*8 && len(vals) < n; k++ {
+			v := 0
+			for bt := 0; bt < width; bt++ {
+				p := k*width + bt
+				if bits[p/8]&(1<<uint(p%8)) != 0 {
+					v |= 1 << uint(bt)
+				}
+			}
+			vals = append(vals, v)
+		}
+	}
+	return vals, nil
+}
+
+// parquetColumn is a column of a Parquet file being read.
+type parquetColumn struct {
+	typ      int64
+	conv     int64
+	length   int
+	optional bool
+	dict     []string
+	vals     []string
+}
+
+// newParquetReader returns a reader for Parquet files. Only flat schemas
+// are supported, and columns compressed with snappy or gzip, or
+// uncompressed.
+func newParquetReader(in io.Reader, param string) (recordReader, error) {
+	data, err := ioutil.ReadAll(in)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 12 || string(data[:4]) != parquetMagic || string(data[len(data)-4:]) != parquetMagic {
+		return nil, errors.New("parquet: invalid file")
+	}
+	mdLen := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	if mdLen > len(data)-12 {
+		return nil, errors.New("parquet: invalid metadata length")
+	}
+	md, err := newThriftReader(bytes.NewReader(data[len(data)-8-mdLen : len(data)-8])).readStruct()
+	if err != nil {
+		return nil, fmt.Errorf("parquet: metadata: %v", err)
+	}
+
+	schema := md.list(2)
+	if len(schema) == 0 {
+		return nil, errors.New("parquet: empty schema")
+	}
+	var header []string
+	var cols []*parquetColumn
+	for _, s := range schema[1:] {
+		el, _ := s.(thriftStructVal)
+		if el.int(5) > 0 {
+			return nil, errors.New("parquet: nested columns are not supported")
+		}
+		if el.int(3) == 2 {
+			return nil, errors.New("parquet: repeated columns are not supported")
+		}
+		header = append(header, string(el.bytes(4)))
+		c := &parquetColumn{
+			typ:      el.int(1),
+			length:   int(el.int(2)),
+			optional: el.int(3) == 1,
+			conv:     -1,
+		}
+		if _, ok := el[6]; ok {
+			c.conv = el.int(6)
+		}
+		cols = append(cols, c)
+	}
+
+	for _, g := range md.list(4) {
+		rg, _ := g.(thriftStructVal)
+		chunks := rg.list(1)
+		if len(chunks) != len(cols) {
+			return nil, errors.New("parquet: invalid row group")
+		}
+		for i, ch := range chunks {
+			cm := ch.(thriftStructVal).strct(3)
+			if cm == nil {
+				return nil, errors.New("parquet: column without metadata")
+			}
+			if err := cols[i].readChunk(data, cm); err != nil {
+				return nil, fmt.Errorf("parquet: column %s: %v", header[i], err)
+			}
+		}
+	}
+
+	r := &memReader{}
+	if len(header) == 0 {
+		return r, nil
+	}
+	r.recs = append(r.recs, header)
+	rows := int(md.int(3))
+	for j := 0; j < rows; j++ {
+		rec := make([]string, len(cols))
+		for i, c := range cols {
+			if j < len(c.vals) {
+				rec[i] = c.vals[j]
+			}
+		}
+		r.recs = append(r.recs, rec)
+	}
+	return r, nil
+}
+
+// readChunk reads the pages of a column chunk.
+func (c *parquetColumn) readChunk(data []byte, cm thriftStructVal) error {
+	codec := cm.int(4)
+	total := int(cm.int(5))
+	pos := cm.int(9)
+	if dp, ok := cm[11]; ok && dp.(int64) > 0 && dp.(int64) < pos {
+		pos = dp.(int64)
+	}
+	c.dict = nil
+	read := 0
+	for read < total {
+		if pos < 0 || pos >= int64(len(data)) {
+			return errors.New("invalid page offset")
+		}
+		br := bytes.NewReader(data[pos:])
+		ph, err := newThriftReader(br).readStruct()
+		if err != nil {
+			return err
+		}
+		start := int64(len(data)) - int64(br.Len())
+		size := ph.int(3)
+		if size < 0 || start+size > int64(len(data)) {
+			return errors.New("invalid page size")
+		}
+		page := data[start : start+size]
+		pos = start + size
+
+		switch ph.int(1) {
+		case pqDictionaryPage:
+			page, err = decompress(codec, page)
+			if err != nil {
+				return err
+			}
+			dh := ph.strct(7)
+			c.dict, err = c.plain(page, int(dh.int(1)))
+			if err != nil {
+				return err
+			}
+		case pqDataPage:
+			page, err = decompress(codec, page)
+			if err != nil {
+				return err
+			}
+			dh := ph.strct(5)
+			n := int(dh.int(1))
+			defs := make([]int, n)
+			if c.optional {
+				if len(page) < 4 {
+					return errors.New("truncated page")
+				}
+				l := int(binary.LittleEndian.Uint32(page))
+				if l > len(page)-4 {
+					return errors.New("truncated page")
+				}
+				if defs, err = decodeHybrid(page[4:4+l], 1, n); err != nil {
+					return err
+				}
+				page = page[4+l:]
+			} else {
+				for i := range defs {
+					defs[i] = 1
+				}
+			}
+			if err := c.values(page, defs, dh.int(2)); err != nil {
+				return err
+			}
+			read += n
+		case pqDataPageV2:
+			dh := ph.strct(8)
+			n := int(dh.int(1))
+			dl, rl := int(dh.int(5)), int(dh.int(6))
+			if dl+rl > len(page) {
+				return errors.New("truncated page")
+			}
+			defs := make([]int, n)
+			if c.optional {
+				if defs, err = decodeHybrid(page[rl:rl+dl], 1, n); err != nil {
+					return err
+				}
+			} else {
+				for i := range defs {
+					defs[i] = 1
+				}
+			}
+			vals := page[rl+dl:]
+			if comp, ok := dh[7].(bool); !ok || comp {
+				if vals, err = decompress(codec, vals); err != nil {
+					return err
+				}
+			}
+			if err := c.values(vals, defs, dh.int(4)); err != nil {
+				return err
+			}
+			read += n
+		}
+	}
+	return nil
+}
+
+// values decodes the values of a data page.
+func (c *parquetColumn) values(page []byte, defs []int, enc int64) error {
+	n := 0
+	for _, d := range defs {
+		n += d
+	}
+	var vals []string
+	switch enc {
+	case pqPlain:
+		var err error
+		if vals, err = c.plain(page, n); err != nil {
+			return err
+		}
+	case pqPlainDict, pqRLEDictionary:
+		if len(page) == 0 {
+			if n > 0 {
+				return errors.New("truncated page")
+			}
+			break
+		}
+		idx, err := decodeHybrid(page[1:], int(page[0]), n)
+		if err != nil {
+			return err
+		}
+		for _, i := range idx {
+			if i >= len(c.dict) {
+				return errors.New("invalid dictionary index")
+			}
+			vals = append(vals, c.dict[i])
+		}
+	default:
+		return fmt.Errorf("unsupported encoding %d", enc)
+	}
+	for _, d := range defs {
+		if d == 0 {
+			c.vals = append(c.vals, "")
+			continue
+		}
+		c.vals = append(c.vals, vals[0])
+		vals = vals[1:]
+	}
+	return nil
+}
+
+// plain decodes n values with the plain encoding.
+func (c *parquetColumn) plain(data []byte, n int) ([]string, error) {
+	vals := make([]string, 0, n)
+	pos := 0
+	size := 0
+	switch c.typ {
+	case pqInt32, pqFloat:
+		size = 4
+	case pqInt64, pqDouble:
+		size = 8
+	case pqFixed:
+		size = c.length
+	case pqBoolean, pqByteArray:
+	default:
+		return nil, fmt.Errorf("unsupported type %d", c.typ)
+	}
+	for i := 0; i < n; i++ {
+		if c.typ == pqBoolean {
+			if i/8 >= len(data) {
+				return nil, errors.New("truncated values")
+			}
+			vals = append(vals, strconv.FormatBool(data[i/8]&(1<<uint(i%8)) != 0))
+			continue
+		}
+		if c.typ == pqByteArray {
+			if pos+4 > len(data) {
+				return nil, errors.New("truncated values")
+			}
+			size = int(binary.LittleEndian.Uint32(data[pos:]))
+			pos += 4
+		}
+		if size < 0 || pos+size > len(data) {
+			return nil, errors.New("truncated values")
+		}
+		v := data[pos : pos+size]
+		pos += size
+		switch c.typ {
+		case pqInt32:
+			vals = append(vals, c.formatInt(int64(int32(binary.LittleEndian.Uint32(v)))))
+		case pqInt64:
+			vals = append(vals, c.formatInt(int64(binary.LittleEndian.Uint64(v))))
+		case pqFloat:
+			vals = append(vals, formatFloat(float64(math.Float32frombits(binary.LittleEndian.Uint32(v)))))
+		case pqDouble:
+			vals = append(vals, formatFloat(math.Float64frombits(binary.LittleEndian.Uint64(v))))
+		default:
+			vals = append(vals, string(v))
+		}
+	}
+	return vals, nil
+}
+
+// formatInt formats an integer using the converted type of the column.
+func (c *parquetColumn) formatInt(v int64) string {
+	switch c.conv {
+	case pqConvDate:
+		return time.Unix(v*86400, 0).UTC().Format("2006-01-02")
+	case pqConvTimestampMillis:
+		return time.Unix(0, v*int64(time.Millisecond)).UTC().Format(time.RFC3339Nano)
+	case pqConvTimestampMicros:
+		return time.Unix(0, v*int64(time.Microsecond)).UTC().Format(time.RFC3339Nano)
+	}
+	return strconv.FormatInt(v, 10)
+}
+
+// decompress decompresses a page.
+func decompress(codec int64, data []byte) ([]byte, error) {
+	switch codec {
+	case 0:
+		return data, nil
+	case 1:
+		return decodeSnappy(data)
+	case 2:
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		return ioutil.ReadAll(zr)
+	}
+	return nil, fmt.Errorf("unsupported compression codec %d", codec)
+}
+
+// decodeSnappy decodes a block compressed with snappy.
+func decodeSnappy(src []byte) ([]byte, error) {
+	errCorrupt := errors.New("corrupt snappy block")
+	n, l := binary.Uvarint(src)
+	if l <= 0 || n > 1<<31 {
+		return nil, errCorrupt
+	}
+	src = src[l:]
+	dst := make([]byte, 0, n)
+	for len(src) > 0 {
+		tag := src[0]
+		switch tag & 3 {
+		case 0:
+			ln := int(tag >> 2)
+			src = src[1:]
+			if ln >= 60 {
+				b := ln - 59
+				if len(src) < b {
+					return nil, errCorrupt
+				}
+				ln = 0
+				for i := 0; i < b; i++ {
+					ln |= int(src[i]) << (8 * uint(i))
+				}
+				src = src[b:]
+			}
+			ln++
+			if ln > len(src) {
+				return nil, errCorrupt
+			}
+			dst = append(dst, src[:ln]...)
+			src = src[ln:]
+			continue
+		case 1:
+			if len(src) < 2 {
+				return nil, errCorrupt
+			}
+			ln := 4 + int(tag>>2)&7
+			off := int(tag>>5)<<8 | int(src[1])
+			src = src[2:]
+			if err := snappyCopy(&dst, off, ln); err != nil {
+				return nil, err
+			}
+		case 2:
+			if len(src) < 3 {
+				return nil, errCorrupt
+			}
+			ln := 1 + int(tag>>2)
+			off := int(binary.LittleEndian.Uint16(src[1:]))
+			src = src[3:]
+			if err := snappyCopy(&dst, off, ln); err != nil {
+				return nil, err
+			}
+		case 3:
+			if len(src) < 5 {
+				return nil, errCorrupt
+			}
+			ln := 1 + int(tag>>2)
+			off := int(binary.LittleEndian.Uint32(src[1:]))
+			src = src[5:]
+			if err := snappyCopy(&dst, off, ln); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if uint64(len(dst)) != n {
+		return nil, errCorrupt
+	}
+	return dst, nil
+}
+
+// snappyCopy copies ln bytes starting at off bytes before the end of dst.
+func snappyCopy(dst *[]byte, off, ln int) error {
+	if off <= 0 || off > len(*dst) {
+		return errors.New("corrupt snappy block")
+	}
+	start := len(*dst) - off
+	for i := 0; i < ln; i++ {
+		*dst = append(*dst, (*dst)[start+i])
+	}
+	return nil
+}
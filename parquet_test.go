@@ -0,0 +1,107 @@
+// Copyright (c) 2016, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+var columnarTable = [][]string{
+	[]string{"Item", "Cost", "Count", "Description"},
+	[]string{"1", "50.5", "", "rubber gloves"},
+	[]string{"2", "", "-3", ""},
+	[]string{"3", "1e+20", "1000000", "test\ttubes"},
+}
+
+func testColumnarRead(t *testing.T, name string, r recordReader, rs [][]string) {
+	for i := 0; ; i++ {
+		row, err := r.Read()
+		if err != nil {
+			if err == io.EOF {
+				if i != len(rs) {
+					t.Errorf("%s: expecting %d records, found %d", name, len(rs), i)
+				}
+				break
+			}
+			t.Errorf("%s: unexpected error: %v", name, err)
+			break
+		}
+		if i >= len(rs) {
+			continue
+		}
+		if len(row) != len(rs[i]) {
+			t.Errorf("%s: expecting %d fields, found %d (row %d)", name, len(rs[i]), len(row), i)
+			continue
+		}
+		for j, v := range rs[i] {
+			if row[j] != v {
+				t.Errorf("%s: expecting %q in row %d col %d, found %q", name, v, i, j, row[j])
+			}
+		}
+	}
+}
+
+func TestParquet(t *testing.T) {
+	var b bytes.Buffer
+	w := newParquetWriter(&b, "")
+	for _, r := range columnarTable {
+		w.Write(r)
+	}
+	if err := w.Flush(); err != nil {
+		t.Errorf("parquet: unexpected error: %v", err)
+	}
+	if !bytes.HasPrefix(b.Bytes(), []byte("PAR1")) || !bytes.HasSuffix(b.Bytes(), []byte("PAR1")) {
+		t.Errorf("parquet: invalid magic number")
+	}
+	r, err := newParquetReader(bytes.NewReader(b.Bytes()), "")
+	if err != nil {
+		t.Fatalf("parquet: unexpected error: %v", err)
+	}
+	testColumnarRead(t, "parquet", r, columnarTable)
+
+	if _, err := newParquetReader(bytes.NewReader(b.Bytes()[:b.Len()-10]), ""); err == nil {
+		t.Errorf("parquet: expecting error on truncated file")
+	}
+}
+
+func TestDecodeHybrid(t *testing.T) {
+	levels := []int{1, 1, 1, 0, 0, 1, 0}
+	vals, err := decodeHybrid(encodeRLE(levels), 1, len(levels))
+	if err != nil {
+		t.Fatalf("parquet: unexpected error: %v", err)
+	}
+	for i, v := range levels {
+		if vals[i] != v {
+			t.Errorf("parquet: expecting level %d at %d, found %d", v, i, vals[i])
+		}
+	}
+
+	// a bit-packed group with values 0..7 in 3 bits
+	vals, err = decodeHybrid([]byte{0x03, 0x88, 0xC6, 0xFA}, 3, 8)
+	if err != nil {
+		t.Fatalf("parquet: unexpected error: %v", err)
+	}
+	for i, v := range vals {
+		if v != i {
+			t.Errorf("parquet: expecting value %d at %d, found %d", i, i, v)
+		}
+	}
+}
+
+func TestSnappy(t *testing.T) {
+	// literal "abcd" followed by a copy of 8 bytes with offset 4
+	b, err := decodeSnappy([]byte{0x0c, 0x0c, 'a', 'b', 'c', 'd', 0x11, 0x04})
+	if err != nil {
+		t.Fatalf("snappy: unexpected error: %v", err)
+	}
+	if string(b) != "abcdabcdabcd" {
+		t.Errorf("snappy: expecting %q, found %q", "abcdabcdabcd", b)
+	}
+	if _, err := decodeSnappy([]byte{0x0c, 0x0c, 'a', 'b', 'c', 'd', 0x11, 0x05}); err == nil {
+		t.Errorf("snappy: expecting error on invalid offset")
+	}
+}
@@ -0,0 +1,357 @@
+// Copyright (c) 2016, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/js-arias/cmdapp"
+)
+
+var pivotCmd = &cmdapp.Command{
+	Run: pivotRun,
+	UsageLine: `pivot [-f <char>] [--dups <policy>] [--encoding <name>]
+	[--from <format>] [-i|--input <file>] [-n|--no-header]
+	[-o|--output <file>] [--ragged <policy>] [--schema <file>]
+	[--to <format>] --columns <column> [--values <column>]
+	[--agg <function>] [--fill <value>] <key>...`,
+	Short: "converts a table from long to wide form",
+	Long: `
+Command pivot reads a table in long form, and outputs a table in wide form,
+with a row for each combination of the values of the key columns, and a
+column for each value of the column set with --columns. The fields of the
+new columns are the values of the column set with --values, aggregated
+with the function set with --agg when several rows have the same key and
+column. The rows and the new columns are in the order in which they are
+found in the input table.
+
+For example, a table with the columns Site, Species, and Count, can be
+converted to a matrix with a row for each site, and a column for each
+species, with:
+
+    tables pivot --columns Species --values Count Site
+
+The aggregation functions are:
+
+    sum
+      The default. The sum of the numeric values.
+
+    mean
+      The mean of the numeric values.
+
+    count
+      The number of non empty values, or if no value column is given, the
+      number of rows.
+
+    first
+      The first non empty value.
+
+Empty values, and in sum and mean, non numeric values, are ignored. If
+there are no values for a field, the field is set to the value of --fill,
+or to 0 with count.
+
+The values of the column set with --columns must not be empty, nor the
+name of a key column, as they are the names of the new columns.
+
+Options are:
+
+    -f <char>
+      Sets the field separation character. By default the value is the tab
+      character.
+
+    --dups <policy>
+      Sets how to handle duplicated column names in the header, one of
+      warn (the default), error, or rename. See 'tables help formats'.
+
+    --encoding <name>
+      Sets the character encoding of the input. By default the encoding
+      is detected from the input. See 'tables help formats' for the
+      available encodings.
+
+    --from <format>
+      Sets the format of the input table. By default the format is
+      detected from the extension of the input file, or it is a delimited
+      text table. See 'tables help formats' for the available formats.
+
+    -i <file>
+    --input <file>
+      Read the table from <file> instead of stdin.
+
+    -n
+    --no-header
+      If set, the table will be printed without a header.
+
+    -o <file>
+    --output <file>
+      Write the resulting table to <file> instead of stdout.
+
+    --ragged <policy>
+      Sets how to handle records with a different number of fields than
+      the header, one of error (the default), pad, truncate, or skip. See
+      'tables help formats'.
+
+    --schema <file>
+      Read the types of the columns of the input from a schema file. See
+      'tables help schema'.
+
+    --to <format>
+      Sets the format of the output table. By default the format is
+      detected from the extension of the output file, or it is a delimited
+      text table. See 'tables help formats' for the available formats.
+
+    --columns <column>
+      The column with the names of the new columns. This option is
+      required.
+
+    --values <column>
+      The column with the values of the new columns. It is required,
+      except with the count function.
+
+    --agg <function>
+      Sets the function used to aggregate the values, one of sum (the
+      default), mean, count, or first.
+
+    --fill <value>
+      Sets the value of the fields without values, except with the count
+      function. By default it is empty.
+
+    <key>
+      One or more key columns, selected as in 'tables help cols'.
+	`,
+}
+
+var pivotColumns string // set the column with the new names, --columns
+var pivotValues string  // set the column with the values, --values
+var pivotAgg string     // set the aggregation function, --agg
+var pivotFill string    // set the value of empty fields, --fill
+
+func init() {
+	initCommonFlags(pivotCmd)
+	pivotCmd.Flag.StringVar(&pivotColumns, "columns", "", "")
+	pivotCmd.Flag.StringVar(&pivotValues, "values", "", "")
+	pivotCmd.Flag.StringVar(&pivotAgg, "agg", "sum", "")
+	pivotCmd.Flag.StringVar(&pivotFill, "fill", "", "")
+}
+
+func pivotRun(c *cmdapp.Command, args []string) error {
+	if len(pivotColumns) == 0 {
+		return errors.New("the column with the new names must be set with --columns")
+	}
+	switch pivotAgg {
+	case "sum", "mean", "first":
+		if len(pivotValues) == 0 {
+			return fmt.Errorf("the column with the values must be set with --values for %s", pivotAgg)
+		}
+	case "count":
+	default:
+		return fmt.Errorf("unknown aggregation function %q", pivotAgg)
+	}
+	r, err := openInput()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	w, err := openOutput()
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	header, err := r.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+	p, err := newPivot(r, header, args)
+	if err != nil {
+		return err
+	}
+	for {
+		row, err := r.Read()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return err
+		}
+		p.add(row)
+	}
+	recs, err := p.table()
+	if err != nil {
+		return err
+	}
+	for _, row := range recs {
+		if err := w.Write(row); err != nil {
+			return err
+		}
+	}
+	return w.Close()
+}
+
+// pivotCell is the aggregation of the values of a field.
+type pivotCell struct {
+	n     int // number of values
+	sum   float64
+	first string
+}
+
+// pivotRow is a row of a pivot table.
+type pivotRow struct {
+	key   []string
+	cells map[string]*pivotCell
+}
+
+// pivot stores the aggregated values of a pivot table.
+type pivot struct {
+	keyNames []string
+	keys     []int // key columns
+	col      int   // column with the new names
+	val      int   // column with the values, -1 if not used
+
+	names  []string // names of the new columns
+	known  map[string]bool
+	rows   []*pivotRow
+	rowIdx map[string]*pivotRow
+}
+
+// newPivot returns a pivot for a table with a given header.
+func newPivot(r recordReader, header, args []string) (*pivot, error) {
+	p := &pivot{
+		val:    -1,
+		known:  make(map[string]bool),
+		rowIdx: make(map[string]*pivotRow),
+	}
+	var err error
+	if p.col, err = findColumn(header, pivotColumns); err != nil {
+		return nil, err
+	}
+	if p.col < 0 {
+		return nil, fmt.Errorf("unknown column %q", pivotColumns)
+	}
+	if len(pivotValues) > 0 {
+		if p.val, err = findColumn(header, pivotValues); err != nil {
+			return nil, err
+		}
+		if p.val < 0 {
+			return nil, fmt.Errorf("unknown column %q", pivotValues)
+		}
+	}
+	sel := &columnSelector{r: r, header: header}
+	for _, a := range args {
+		idx, err := sel.columns(a)
+		if err != nil {
+			return nil, err
+		}
+		for _, j := range idx {
+			if j < 0 {
+				return nil, fmt.Errorf("unknown column %q", a)
+			}
+			if j == p.col || j == p.val {
+				return nil, fmt.Errorf("column %q can not be a key", header[j])
+			}
+			p.keys = append(p.keys, j)
+			p.keyNames = append(p.keyNames, header[j])
+		}
+	}
+	return p, nil
+}
+
+// add adds a row of the input table.
+func (p *pivot) add(rec []string) {
+	key := make([]string, len(p.keys))
+	for i, k := range p.keys {
+		key[i] = cell(rec, k)
+	}
+	id := strings.Join(key, "\x00")
+	row, ok := p.rowIdx[id]
+	if !ok {
+		row = &pivotRow{key: key, cells: make(map[string]*pivotCell)}
+		p.rowIdx[id] = row
+		p.rows = append(p.rows, row)
+	}
+
+	name := cell(rec, p.col)
+	if !p.known[name] {
+		p.known[name] = true
+		p.names = append(p.names, name)
+	}
+	c, ok := row.cells[name]
+	if !ok {
+		c = &pivotCell{}
+		row.cells[name] = c
+	}
+	if p.val < 0 {
+		c.n++
+		return
+	}
+	v := cell(rec, p.val)
+	if len(v) == 0 {
+		return
+	}
+	switch pivotAgg {
+	case "sum", "mean":
+		x, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return
+		}
+		c.sum += x
+	case "first":
+		if c.n == 0 {
+			c.first = v
+		}
+	}
+	c.n++
+}
+
+// table returns the rows of the pivot table, including the header. It
+// returns an error if a new column has an empty name, or the name of a key
+// column.
+func (p *pivot) table() ([][]string, error) {
+	for _, nm := range p.names {
+		if len(nm) == 0 {
+			return nil, fmt.Errorf("column %q has an empty value, that can not be a column name", pivotColumns)
+		}
+		for i, k := range p.keyNames {
+			if nm == k {
+				return nil, fmt.Errorf("duplicated column name %q: key column #%d and a new column", nm, i+1)
+			}
+		}
+	}
+	header := append(append([]string{}, p.keyNames...), p.names...)
+	recs := [][]string{header}
+	for _, row := range p.rows {
+		rec := append([]string{}, row.key...)
+		for _, nm := range p.names {
+			rec = append(rec, row.cells[nm].value())
+		}
+		recs = append(recs, rec)
+	}
+	return recs, nil
+}
+
+// value returns the aggregated value of a field.
+func (c *pivotCell) value() string {
+	if pivotAgg == "count" {
+		if c == nil {
+			return "0"
+		}
+		return strconv.Itoa(c.n)
+	}
+	if c == nil || c.n == 0 {
+		return pivotFill
+	}
+	switch pivotAgg {
+	case "sum":
+		return strconv.FormatFloat(c.sum, 'f', -1, 64)
+	case "mean":
+		return strconv.FormatFloat(c.sum/float64(c.n), 'f', -1, 64)
+	}
+	return c.first
+}
@@ -0,0 +1,84 @@
+// Copyright (c) 2016, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPivot(t *testing.T) {
+	recs := [][]string{
+		[]string{"Site", "Species", "Count"},
+		[]string{"A", "sp1", "2"},
+		[]string{"A", "sp2", "3"},
+		[]string{"B", "sp1", "1"},
+		[]string{"A", "sp1", "4"},
+		[]string{"B", "sp3", ""},
+	}
+	tests := []struct {
+		agg, values, fill string
+		rows              []string
+	}{
+		{"sum", "Count", "0", []string{"Site sp1 sp2 sp3", "A 6 3 0", "B 1 0 0"}},
+		{"mean", "Count", "", []string{"Site sp1 sp2 sp3", "A 3 3 ", "B 1  "}},
+		{"first", "Count", "-", []string{"Site sp1 sp2 sp3", "A 2 3 -", "B 1 - -"}},
+		{"count", "Count", "-", []string{"Site sp1 sp2 sp3", "A 2 1 0", "B 1 0 0"}},
+		{"count", "", "-", []string{"Site sp1 sp2 sp3", "A 2 1 0", "B 1 0 1"}},
+	}
+	defer func() { pivotColumns, pivotValues, pivotAgg, pivotFill = "", "", "sum", "" }()
+	for _, ts := range tests {
+		pivotColumns, pivotValues, pivotAgg, pivotFill = "Species", ts.values, ts.agg, ts.fill
+		r := &memReader{recs: append([][]string{}, recs[1:]...)}
+		p, err := newPivot(r, recs[0], []string{"Site"})
+		if err != nil {
+			t.Fatalf("Pivot: unexpected error: %v", err)
+		}
+		for {
+			rec, err := r.Read()
+			if err != nil {
+				break
+			}
+			p.add(rec)
+		}
+		recs, err := p.table()
+		if err != nil {
+			t.Errorf("Pivot: %s: unexpected error: %v", ts.agg, err)
+			continue
+		}
+		var rows []string
+		for _, rec := range recs {
+			rows = append(rows, strings.Join(rec, " "))
+		}
+		if strings.Join(rows, "|") != strings.Join(ts.rows, "|") {
+			t.Errorf("Pivot: %s: expecting %q, found %q", ts.agg, ts.rows, rows)
+		}
+	}
+
+	pivotColumns, pivotValues, pivotAgg, pivotFill = "Species", "Count", "sum", ""
+	p, err := newPivot(&memReader{}, recs[0], []string{"Site"})
+	if err != nil {
+		t.Fatalf("Pivot: unexpected error: %v", err)
+	}
+	p.add([]string{"A", "sp1", "600000"})
+	p.add([]string{"A", "sp1", "400000"})
+	if tb, _ := p.table(); tb[1][1] != "1000000" {
+		t.Errorf("Pivot: large sum: expecting %q, found %q", "1000000", tb[1][1])
+	}
+
+	for _, nm := range []string{"", "Site"} {
+		p, _ := newPivot(&memReader{}, recs[0], []string{"Site"})
+		p.add([]string{"A", nm, "1"})
+		if _, err := p.table(); err == nil {
+			t.Errorf("Pivot: column name %q: expecting error", nm)
+		}
+	}
+
+	for _, keys := range [][]string{{"Species"}, {"Place"}} {
+		if _, err := newPivot(&memReader{}, recs[0], keys); err == nil {
+			t.Errorf("Pivot: keys %v: expecting error", keys)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+// Copyright (c) 2016, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"unicode"
+)
+
+// prettyWidth is the maximum display width of a cell in a pretty table.
+const prettyWidth = 40
+
+// prettyWriter writes a table with aligned columns and box-drawing borders.
+// As the widths of the columns depend on all the rows, the table is written
+// when Flush is called.
+type prettyWriter struct {
+	out  io.Writer
+	head bool // the first record is the header
+	recs [][]string
+}
+
+// newPrettyWriter returns a writer for pretty tables.
+func newPrettyWriter(out io.Writer, param string) recordWriter {
+	return &prettyWriter{out: out, head: !noHead}
+}
+
+func (w *prettyWriter) Write(record []string) error {
+	rec := make([]string, len(record))
+	for i, f := range record {
+		rec[i] = truncateCell(f, prettyWidth)
+	}
+	w.recs = append(w.recs, rec)
+	return nil
+}
+
+func (w *prettyWriter) Flush() error {
+	if w.out == io.Writer(os.Stdout) && isTerminal(os.Stdout) && len(os.Getenv("PAGER")) > 0 {
+		return w.page(os.Getenv("PAGER"))
+	}
+	return w.render(w.out)
+}
+
+// page writes the table through a pager.
+func (w *prettyWriter) page(pager string) error {
+	cmd := exec.Command("sh", "-c", pager)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	// write errors are ignored, as the user can close the pager
+	// before the whole table is written
+	w.render(in)
+	in.Close()
+	return cmd.Wait()
+}
+
+// render writes the table.
+func (w *prettyWriter) render(out io.Writer) error {
+	var widths []int
+	for _, rec := range w.recs {
+		for i, f := range rec {
+			if i >= len(widths) {
+				widths = append(widths, 0)
+			}
+			if l := displayWidth(f); l > widths[i] {
+				widths[i] = l
+			}
+		}
+	}
+	bw := bufio.NewWriter(out)
+	rule := func(left, mid, right string) {
+		bw.WriteString(left)
+		for i, l := range widths {
+			if i > 0 {
+				bw.WriteString(mid)
+			}
+			bw.WriteString(strings.Repeat("─", l+2))
+		}
+		bw.WriteString(right + "\n")
+	}
+	rule("┌", "┬", "┐")
+	for i, rec := range w.recs {
+		bw.WriteString("│")
+		for j, l := range widths {
+			f := ""
+			if j < len(rec) {
+				f = rec[j]
+			}
+			pad := strings.Repeat(" ", l-displayWidth(f))
+			bw.WriteString(" ")
+			if _, ok := getFieldValue(f).(float64); ok && (i > 0 || !w.head) {
+				bw.WriteString(pad + f)
+			} else {
+				bw.WriteString(f + pad)
+			}
+			bw.WriteString(" │")
+		}
+		bw.WriteString("\n")
+		if i == 0 && w.head && len(w.recs) > 1 {
+			rule("├", "┼", "┤")
+		}
+	}
+	rule("└", "┴", "┘")
+	return bw.Flush()
+}
+
+// isTerminal returns true if the file is a terminal.
+func isTerminal(f *os.File) bool {
+	st, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return st.Mode()&os.ModeCharDevice != 0
+}
+
+// truncateCell replaces control characters of a cell with spaces, and
+// truncates the cell if its display width is greater than max.
+func truncateCell(f string, max int) string {
+	f = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, f)
+	if displayWidth(f) <= max {
+		return f
+	}
+	var b strings.Builder
+	w := 0
+	for _, r := range f {
+		rw := runeWidth(r)
+		if w+rw >= max {
+			break
+		}
+		w += rw
+		b.WriteRune(r)
+	}
+	b.WriteRune('…')
+	return b.String()
+}
+
+// displayWidth returns the number of terminal columns used by a string.
+func displayWidth(s string) int {
+	w := 0
+	for _, r := range s {
+		w += runeWidth(r)
+	}
+	return w
+}
+
+// wideRunes are the ranges of East Asian wide and full-width characters.
+var wideRunes = [][2]rune{
+	{0x1100, 0x115F},
+	{0x2E80, 0x303E},
+	{0x3041, 0x33FF},
+	{0x3400, 0x4DBF},
+	{0x4E00, 0x9FFF},
+	{0xA000, 0xA4CF},
+	{0xAC00, 0xD7A3},
+	{0xF900, 0xFAFF},
+	{0xFE30, 0xFE4F},
+	{0xFF00, 0xFF60},
+	{0xFFE0, 0xFFE6},
+	{0x1F300, 0x1F64F},
+	{0x1F900, 0x1F9FF},
+	{0x20000, 0x2FFFD},
+	{0x30000, 0x3FFFD},
+}
+
+// runeWidth returns the number of terminal columns used by a rune.
+func runeWidth(r rune) int {
+	if unicode.IsControl(r) || unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf) {
+		return 0
+	}
+	for _, wr := range wideRunes {
+		if r < wr[0] {
+			break
+		}
+		if r <= wr[1] {
+			return 2
+		}
+	}
+	return 1
+}
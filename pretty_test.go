@@ -0,0 +1,52 @@
+// Copyright (c) 2016, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPrettyWrite(t *testing.T) {
+	rs := [][]string{
+		[]string{"Item", "Description"},
+		[]string{"1", "rubber gloves"},
+		[]string{"12", "東京"},
+	}
+	var b bytes.Buffer
+	w := &prettyWriter{out: &b, head: true}
+	for _, r := range rs {
+		w.Write(r)
+	}
+	if err := w.Flush(); err != nil {
+		t.Errorf("Pretty: unexpected error: %v", err)
+	}
+	exp := `┌──────┬───────────────┐
+│ Item │ Description   │
+├──────┼───────────────┤
+│    1 │ rubber gloves │
+│   12 │ 東京          │
+└──────┴───────────────┘
+`
+	if b.String() != exp {
+		t.Errorf("Pretty: expecting:\n%s\nfound:\n%s", exp, b.String())
+	}
+}
+
+func TestTruncateCell(t *testing.T) {
+	tests := []struct {
+		in, out string
+	}{
+		{"short", "short"},
+		{"a longer cell", "a lo…"},
+		{"東京都庁", "東京…"},
+		{"a\tb", "a b"},
+	}
+	for _, c := range tests {
+		if s := truncateCell(c.in, 5); s != c.out {
+			t.Errorf("Pretty: truncate %q: expecting %q, found %q", c.in, c.out, s)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+// Copyright (c) 2016, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// colType is the declared type of a column.
+type colType int
+
+// column types
+const (
+	anyType    colType = iota // guessed from each value
+	stringType                // always a string
+	numberType                // always a number
+)
+
+// typedReader is a table reader that knows the types of its columns.
+type typedReader interface {
+	recordReader
+
+	// Types returns the types of the columns, or nil if the types are
+	// unknown. It is valid only after the header is read.
+	Types() []colType
+}
+
+// columnTypes returns the types of the columns of a table, or nil if the
+// types are unknown.
+func columnTypes(r recordReader) []colType {
+	if tr, ok := r.(typedReader); ok {
+		return tr.Types()
+	}
+	return nil
+}
+
+// typedFieldValue returns the value of a field using the type of its
+// column. If the column is numeric, and the field is not a number, it
+// returns nil.
+func typedFieldValue(field string, types []colType, col int) interface{} {
+	if col >= len(types) {
+		return getFieldValue(field)
+	}
+	switch types[col] {
+	case stringType:
+		return field
+	case numberType:
+		v, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if err != nil {
+			return nil
+		}
+		return v
+	}
+	return getFieldValue(field)
+}
+
+// rdbReader reads a table with an /RDB header, i.e. a header with the
+// names of the columns, followed by a line with the column definitions.
+type rdbReader struct {
+	r     *csvReader
+	types []colType
+}
+
+// newRDBReader returns a reader for /RDB tables.
+func newRDBReader(in io.Reader, param string) (recordReader, error) {
+	return &rdbReader{r: newCSVReader(in)}, nil
+}
+
+func (r *rdbReader) Read() ([]string, error) {
+	if r.types != nil {
+		return r.r.Read()
+	}
+	header, err := r.r.Read()
+	if err != nil {
+		return nil, err
+	}
+	defs, err := r.r.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil, fmt.Errorf("rdb: expecting column definitions")
+		}
+		return nil, err
+	}
+	if len(defs) != len(header) {
+		return nil, fmt.Errorf("rdb: expecting %d column definitions, found %d", len(header), len(defs))
+	}
+	r.types = make([]colType, len(header))
+	for i, d := range defs {
+		t, err := parseRDBDef(d)
+		if err != nil {
+			ln, _ := r.r.FieldPos(i)
+			return nil, &readError{pos: fmt.Sprintf("line %d", ln), column: header[i], err: err}
+		}
+		r.types[i] = t
+	}
+	return header, nil
+}
+
+func (r *rdbReader) FieldPos(field int) (line, column int) {
+	return r.r.FieldPos(field)
+}
+
+func (r *rdbReader) errorField(pe *csv.ParseError) int {
+	return r.r.errorField(pe)
+}
+
+func (r *rdbReader) Types() []colType {
+	return r.types
+}
+
+// parseRDBDef returns the type of a column definition. A definition is
+// made of an optional width, an optional type letter (N for numeric, S
+// for string, and others, as D for dates, treated as strings), and an
+// optional justification (< or >), surrounded by any number of dashes.
+func parseRDBDef(def string) (colType, error) {
+	d := strings.Trim(strings.TrimSpace(def), "-")
+	d = strings.TrimRight(d, "<>")
+	d = strings.TrimLeftFunc(d, unicode.IsDigit)
+	switch strings.ToUpper(d) {
+	case "":
+		return anyType, nil
+	case "N":
+		return numberType, nil
+	}
+	if len(d) == 1 && unicode.IsLetter(rune(d[0])) {
+		return stringType, nil
+	}
+	return anyType, fmt.Errorf("rdb: invalid column definition %q", def)
+}
+
+// rdbWriter writes a table with an /RDB header. The width and type of
+// each column is inferred from the data, so the table is written when
+// Flush is called.
+type rdbWriter struct {
+	w    textWriter
+	recs [][]string
+}
+
+// newRDBWriter returns a writer for /RDB tables.
+func newRDBWriter(out io.Writer, param string) recordWriter {
+	return &rdbWriter{w: newTextWriter(out, "").(textWriter)}
+}
+
+func (w *rdbWriter) Write(record []string) error {
+	w.recs = append(w.recs, append([]string{}, record...))
+	return nil
+}
+
+func (w *rdbWriter) Flush() error {
+	if len(w.recs) == 0 {
+		return w.w.Flush()
+	}
+	header := w.recs[0]
+	defs := make([]string, len(header))
+	for i := range header {
+		width := 1
+		num, text := false, false
+		for _, rec := range w.recs[1:] {
+			f := cell(rec, i)
+			if l := displayWidth(f); l > width {
+				width = l
+			}
+			if len(f) == 0 {
+				continue
+			}
+			if _, ok := getFieldValue(f).(float64); ok {
+				num = true
+			} else {
+				text = true
+			}
+		}
+		t := "S"
+		if num && !text {
+			t = "N"
+		}
+		defs[i] = strconv.Itoa(width) + t
+	}
+	if err := w.w.Write(header); err != nil {
+		return err
+	}
+	if err := w.w.Write(defs); err != nil {
+		return err
+	}
+	for _, rec := range w.recs[1:] {
+		if err := w.w.Write(rec); err != nil {
+			return err
+		}
+	}
+	return w.w.Flush()
+}
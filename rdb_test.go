@@ -0,0 +1,91 @@
+// Copyright (c) 2016, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+var rdbBlob = `Code	Value	Name
+4S	-----N	10
+10	5	first
+9	12	second
+`
+
+func TestRDBRead(t *testing.T) {
+	delim = "\t"
+	r, err := newRDBReader(strings.NewReader(rdbBlob), "")
+	if err != nil {
+		t.Errorf("RDB: unexpected error: %v", err)
+	}
+	header, err := r.Read()
+	if err != nil {
+		t.Errorf("RDB: unexpected error: %v", err)
+	}
+	if len(header) != 3 || header[0] != "Code" {
+		t.Errorf("RDB: unexpected header %v", header)
+	}
+	types := columnTypes(r)
+	exp := []colType{stringType, numberType, anyType}
+	for i, tp := range exp {
+		if types[i] != tp {
+			t.Errorf("RDB: column %d: expecting type %d, found %d", i, tp, types[i])
+		}
+	}
+
+	// as Code is a string "10" < "9"
+	e, err := parseExpression(header, strings.NewReader(`Code < "9"`))
+	if err != nil {
+		t.Errorf("RDB: unexpected error on expression: %v", err)
+	}
+	n := 0
+	for {
+		row, err := rowsFn(r, []expression{e})
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			t.Errorf("RDB: unexpected error on read: %v", err)
+			break
+		}
+		if len(row) == 0 {
+			continue
+		}
+		n++
+		if row[0] != "10" {
+			t.Errorf("RDB: unexpected row %v", row)
+		}
+	}
+	if n != 1 {
+		t.Errorf("RDB: expecting %d rows, found %d", 1, n)
+	}
+
+	if _, err := parseRDBDef("5X3"); err == nil {
+		t.Errorf("RDB: expecting error on invalid definition")
+	}
+}
+
+func TestRDBWrite(t *testing.T) {
+	delim = "\t"
+	var b bytes.Buffer
+	w := newRDBWriter(&b, "")
+	for _, r := range [][]string{
+		[]string{"Code", "Value", "Name"},
+		[]string{"10", "5", "first"},
+		[]string{"9", "", "a second"},
+	} {
+		w.Write(r)
+	}
+	if err := w.Flush(); err != nil {
+		t.Errorf("RDB: unexpected error: %v", err)
+	}
+	exp := "Code\tValue\tName\r\n2N\t1N\t8S\r\n10\t5\tfirst\r\n9\t\ta second\r\n"
+	if b.String() != exp {
+		t.Errorf("RDB: expecting %q, found %q", exp, b.String())
+	}
+}
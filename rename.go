@@ -0,0 +1,291 @@
+// Copyright (c) 2016, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/js-arias/cmdapp"
+)
+
+var renameCmd = &cmdapp.Command{
+	Run: renameRun,
+	UsageLine: `rename [-f <char>] [--dups <policy>] [--encoding <name>]
+	[--from <format>] [-i|--input <file>] [-n|--no-header]
+	[-o|--output <file>] [--ragged <policy>] [--schema <file>]
+	[--to <format>] [--case <mode>] <rule>...`,
+	Short: "renames the columns of a table",
+	Long: `
+Command rename changes the names of the columns of a table, and outputs
+the table with the new header. The rows of the table are not modified.
+
+A rule can be a pair old=new, that renames the column old (a name, or a
+position, as in #3) as new, or a substitution s/<regexp>/<replacement>/,
+that replaces the matches of the regular expression in all the column
+names. In the replacement, $1 or ${1} is the text of the first submatch,
+and so on (use $$ for a literal $). To use a slash in the expression or
+the replacement, escape it with a backslash (\/). The rules are applied in
+the given order, after the case normalization of the --case option, so
+each rule uses the names produced by the previous rules.
+
+If a rule renames a column that is not in the table, or if the resulting
+header has duplicated column names, the command ends with an error.
+
+Because the substitutions use special characters, they must be enclosed
+in single quotes (') to protect them from being interpreted by the shell.
+
+Options are:
+
+    -f <char>
+      Sets the field separation character. By default the value is the tab
+      character.
+
+    --dups <policy>
+      Sets how to handle duplicated column names in the header, one of
+      warn (the default), error, or rename. See 'tables help formats'.
+
+    --encoding <name>
+      Sets the character encoding of the input. By default the encoding
+      is detected from the input. See 'tables help formats' for the
+      available encodings.
+
+    --from <format>
+      Sets the format of the input table. By default the format is
+      detected from the extension of the input file, or it is a delimited
+      text table. See 'tables help formats' for the available formats.
+
+    -i <file>
+    --input <file>
+      Read the table from <file> instead of stdin.
+
+    -n
+    --no-header
+      If set, the table will be printed without a header.
+
+    -o <file>
+    --output <file>
+      Write the resulting table to <file> instead of stdout.
+
+    --ragged <policy>
+      Sets how to handle records with a different number of fields than
+      the header, one of error (the default), pad, truncate, or skip. See
+      'tables help formats'.
+
+    --schema <file>
+      Read the types of the columns of the input from a schema file. See
+      'tables help schema'.
+
+    --to <format>
+      Sets the format of the output table. By default the format is
+      detected from the extension of the output file, or it is a delimited
+      text table. See 'tables help formats' for the available formats.
+
+    --case <mode>
+      Changes the case of all the column names, one of lower, upper, or
+      title (the first letter of each word in upper case, and the other
+      letters in lower case).
+
+    <rule>
+      One or more rename rules.
+	`,
+}
+
+var renameCase string // set the case of the names, --case
+
+func init() {
+	initCommonFlags(renameCmd)
+	renameCmd.Flag.StringVar(&renameCase, "case", "", "")
+}
+
+func renameRun(c *cmdapp.Command, args []string) error {
+	if len(args) == 0 && len(renameCase) == 0 {
+		c.Usage()
+	}
+	var rules []renameRule
+	if len(renameCase) > 0 {
+		rl, err := caseRule(renameCase)
+		if err != nil {
+			return err
+		}
+		rules = append(rules, rl)
+	}
+	for _, a := range args {
+		rl, err := parseRenameRule(a)
+		if err != nil {
+			return err
+		}
+		rules = append(rules, rl)
+	}
+
+	r, err := openInput()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	w, err := openOutput()
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	header, err := r.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+	header, err = renameHeader(header, rules)
+	if err != nil {
+		return err
+	}
+	if err := w.Write(header); err != nil {
+		return err
+	}
+	for {
+		row, err := r.Read()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return err
+		}
+		if err := w.Write(row); err != nil {
+			return err
+		}
+	}
+	return w.Close()
+}
+
+// renameRule is a rule to rename the columns of a header.
+type renameRule struct {
+	rule string // the rule as given by the user
+
+	// a pair old=new
+	old, new string
+
+	// a substitution, or a case change
+	re   *regexp.Regexp
+	repl string
+	fn   func(string) string
+}
+
+// parseRenameRule returns a rename rule from a string.
+func parseRenameRule(s string) (renameRule, error) {
+	if strings.HasPrefix(s, "s/") {
+		parts := splitSubstitution(s[2:])
+		if len(parts) != 3 || len(parts[2]) > 0 {
+			return renameRule{}, fmt.Errorf("invalid rule %q: expecting s/<regexp>/<replacement>/", s)
+		}
+		re, err := regexp.Compile(parts[0])
+		if err != nil {
+			return renameRule{}, fmt.Errorf("invalid rule %q: %v", s, err)
+		}
+		return renameRule{rule: s, re: re, repl: parts[1]}, nil
+	}
+	i := strings.Index(s, "=")
+	if i <= 0 || i == len(s)-1 {
+		return renameRule{}, fmt.Errorf("invalid rule %q: expecting old=new", s)
+	}
+	return renameRule{rule: s, old: s[:i], new: s[i+1:]}, nil
+}
+
+// splitSubstitution splits the fields of a substitution at the slashes
+// not escaped by a backslash. Escaped slashes are unescaped.
+func splitSubstitution(s string) []string {
+	var parts []string
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s) && s[i+1] == '/':
+			b.WriteByte('/')
+			i++
+		case s[i] == '/':
+			parts = append(parts, b.String())
+			b.Reset()
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return append(parts, b.String())
+}
+
+// caseRule returns a rule that changes the case of the column names.
+func caseRule(mode string) (renameRule, error) {
+	rl := renameRule{rule: "--case " + mode}
+	switch strings.ToLower(mode) {
+	case "lower":
+		rl.fn = strings.ToLower
+	case "upper":
+		rl.fn = strings.ToUpper
+	case "title":
+		rl.fn = titleCase
+	default:
+		return renameRule{}, fmt.Errorf("unknown case %q", mode)
+	}
+	return rl, nil
+}
+
+// titleCase returns a string with the first letter of each word in upper
+// case, and the other letters in lower case.
+func titleCase(s string) string {
+	var b strings.Builder
+	prev := ' '
+	for len(s) > 0 {
+		r, n := utf8.DecodeRuneInString(s)
+		s = s[n:]
+		if unicode.IsLetter(prev) || unicode.IsDigit(prev) {
+			b.WriteRune(unicode.ToLower(r))
+		} else {
+			b.WriteRune(unicode.ToUpper(r))
+		}
+		prev = r
+	}
+	return b.String()
+}
+
+// renameHeader returns a new header, after applying the rename rules. It
+// returns an error if a renamed column is not found, or if the new header
+// has duplicated names.
+func renameHeader(header []string, rules []renameRule) ([]string, error) {
+	cols := make([]string, len(header))
+	copy(cols, header)
+	for _, rl := range rules {
+		if len(rl.old) > 0 {
+			i, err := findColumn(cols, rl.old)
+			if err != nil {
+				return nil, fmt.Errorf("rule %q: %v", rl.rule, err)
+			}
+			if i < 0 {
+				return nil, fmt.Errorf("rule %q: unknown column %q", rl.rule, rl.old)
+			}
+			cols[i] = rl.new
+			continue
+		}
+		for i, c := range cols {
+			if rl.fn != nil {
+				cols[i] = rl.fn(c)
+				continue
+			}
+			cols[i] = rl.re.ReplaceAllString(c, rl.repl)
+		}
+	}
+
+	seen := make(map[string]int)
+	for i, c := range cols {
+		if len(c) == 0 {
+			return nil, fmt.Errorf("column %q renamed as an empty name", header[i])
+		}
+		if j, ok := seen[c]; ok {
+			return nil, fmt.Errorf("duplicated column name %q: columns #%d and #%d", c, j+1, i+1)
+		}
+		seen[c] = i
+	}
+	return cols, nil
+}
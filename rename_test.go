@@ -0,0 +1,71 @@
+// Copyright (c) 2016, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenameHeader(t *testing.T) {
+	header := []string{"sp_name", "sp_code", "site", "Total count"}
+	tests := []struct {
+		mode  string
+		rules []string
+		cols  string // empty if an error is expected
+	}{
+		{"", []string{"site=Site"}, "sp_name sp_code Site Total count"},
+		{"", []string{"s/^sp_//"}, "name code site Total count"},
+		{"", []string{"s/^sp_(.*)$/${1}_sp/", "site=locality"}, "name_sp code_sp locality Total count"},
+		{"", []string{`s/_/\//`}, "sp/name sp/code site Total count"},
+		{"upper", nil, "SP_NAME SP_CODE SITE TOTAL COUNT"},
+		{"title", []string{"s/ //"}, "Sp_Name Sp_Code Site TotalCount"},
+		{"lower", []string{"total count=count"}, "sp_name sp_code site count"},
+		{"", []string{"s/^sp_.*/name/"}, ""},
+		{"", []string{"site=sp_code"}, ""},
+		{"", []string{"locality=site"}, ""},
+		{"", []string{"s/.*//"}, ""},
+	}
+	for _, ts := range tests {
+		var rules []renameRule
+		if len(ts.mode) > 0 {
+			rl, err := caseRule(ts.mode)
+			if err != nil {
+				t.Fatalf("Rename: unexpected error: %v", err)
+			}
+			rules = append(rules, rl)
+		}
+		for _, a := range ts.rules {
+			rl, err := parseRenameRule(a)
+			if err != nil {
+				t.Fatalf("Rename: unexpected error: %v", err)
+			}
+			rules = append(rules, rl)
+		}
+		cols, err := renameHeader(header, rules)
+		if len(ts.cols) == 0 {
+			if err == nil {
+				t.Errorf("Rename: rules %v: expecting error, found %v", ts.rules, cols)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Rename: rules %v: unexpected error: %v", ts.rules, err)
+			continue
+		}
+		if s := strings.Join(cols, " "); s != ts.cols {
+			t.Errorf("Rename: rules %v: expecting %q, found %q", ts.rules, ts.cols, s)
+		}
+	}
+
+	for _, s := range []string{"site", "=site", "site=", "s/^sp_/", "s/(/x/"} {
+		if _, err := parseRenameRule(s); err == nil {
+			t.Errorf("Rename: rule %q: expecting error", s)
+		}
+	}
+	if _, err := caseRule("camel"); err == nil {
+		t.Errorf("Rename: expecting error on unknown case")
+	}
+}
@@ -6,11 +6,8 @@ package main
 
 import (
 	"bytes"
-	"encoding/csv"
-	"errors"
 	"fmt"
 	"io"
-	"os"
 	"strconv"
 	"strings"
 	"unicode"
@@ -20,8 +17,10 @@ import (
 
 var rowsCmd = &cmdapp.Command{
 	Run: rowsRun,
-	UsageLine: `rows [-f <char>] [-i|--input <file>] [-n|--no-header]
-	[-o|--output <file>] [-v|--invert] <expression>...`,
+	UsageLine: `rows [-f <char>] [--dups <policy>] [--encoding <name>]
+	[--from <format>] [-i|--input <file>] [-n|--no-header]
+	[-o|--output <file>] [--ragged <policy>] [--schema <file>]
+	[--to <format>] [-v|--invert] <expression>...`,
 	Short: "Select rows matching an expression",
 	Long: `
 Command rows select rows that fullfill the conditions given in the expression.
@@ -46,6 +45,20 @@ Options are:
       Sets the field separation character. By default the value is the tab
       character.
 
+    --dups <policy>
+      Sets how to handle duplicated column names in the header, one of
+      warn (the default), error, or rename. See 'tables help formats'.
+
+    --encoding <name>
+      Sets the character encoding of the input. By default the encoding
+      is detected from the input. See 'tables help formats' for the
+      available encodings.
+
+    --from <format>
+      Sets the format of the input table. By default the format is
+      detected from the extension of the input file, or it is a delimited
+      text table. See 'tables help formats' for the available formats.
+
     -i <file>
     --input <file>
       Read the table from <file> instead of stdin.
@@ -58,6 +71,20 @@ Options are:
     --output <file>
       Write the resulting table to <file> instead of stdout. 
 
+    --ragged <policy>
+      Sets how to handle records with a different number of fields than
+      the header, one of error (the default), pad, truncate, or skip. See
+      'tables help formats'.
+
+    --schema <file>
+      Read the types of the columns of the input from a schema file. See
+      'tables help schema'.
+
+    --to <format>
+      Sets the format of the output table. By default the format is
+      detected from the extension of the output file, or it is a delimited
+      text table. See 'tables help formats' for the available formats.
+
     -v
     --invert
       Inverts the program behavior, i.e. output only the columns NOT included
@@ -76,51 +103,32 @@ func rowsRun(c *cmdapp.Command, args []string) error {
 	if len(args) == 0 {
 		c.Usage()
 	}
-	in := os.Stdin
-	if len(input) > 0 {
-		var err error
-		in, err = os.Open(input)
-		if err != nil {
-			return err
-		}
-		defer in.Close()
-	}
-	out := os.Stdout
-	if len(output) > 0 {
-		var err error
-		out, err = os.Create(output)
-		if err != nil {
-			return err
-		}
-		defer out.Close()
+	r, err := openInput()
+	if err != nil {
+		return err
 	}
-	if len(delim) == 0 {
-		delim = "\t"
+	defer r.Close()
+	w, err := openOutput()
+	if err != nil {
+		return err
 	}
-	r1 := []rune(delim)[0]
-	r := csv.NewReader(in)
-	r.Comma = r1
+	defer w.Close()
 	header, err := r.Read()
 	if err != nil {
 		return err
 	}
 	var exps []expression
 	for _, a := range args {
-		e, err := parseExpression(header, strings.NewReader(strings.TrimSpace(a)))
+		a = strings.TrimSpace(a)
+		e, err := parseExpression(header, strings.NewReader(a))
 		if err != nil {
-			return err
+			return expressionError(a, err)
 		}
 		exps = append(exps, e)
 	}
-	w := csv.NewWriter(out)
-	w.Comma = r1
-	w.UseCRLF = true
-	defer w.Flush()
-	if !noHead {
-		err = w.Write(header)
-		if err != nil {
-			return err
-		}
+	err = w.Write(header)
+	if err != nil {
+		return err
 	}
 
 	for {
@@ -139,25 +147,27 @@ func rowsRun(c *cmdapp.Command, args []string) error {
 			return err
 		}
 	}
-	return nil
+	return w.Close()
 }
 
 // rowsFn returns a row if it fullfills the indicated expressions, otherwise
-// it returns an empty row.
-func rowsFn(r *csv.Reader, exps []expression) (row []string, err error) {
+// it returns an empty row. If the reader declares the types of the columns,
+// values are compared using that types.
+func rowsFn(r recordReader, exps []expression) (row []string, err error) {
 	row, err = r.Read()
 	if err != nil {
 		return nil, err
 	}
+	types := columnTypes(r)
 	sel := false
 	for _, e := range exps {
-		val1 := getFieldValue(row[e.cols[0]])
+		val1 := typedFieldValue(cell(row, e.cols[0]), types, e.cols[0])
 		if val1 == nil {
 			continue
 		}
 		val2 := e.value
 		if e.cols[1] != -1 {
-			val2 = getFieldValue(row[e.cols[1]])
+			val2 = typedFieldValue(cell(row, e.cols[1]), types, e.cols[1])
 		}
 		if compare(val1, val2, e.op) {
 			sel = true
@@ -272,17 +282,19 @@ const (
 // comparative expression.
 func parseExpression(header []string, r *strings.Reader) (e expression, err error) {
 	var b bytes.Buffer
+	pos := func() int { return int(r.Size()) - r.Len() }
+	incomplete := func() error { return &exprError{pos(), "incomplete expression"} }
 
 	// get the column name
 	for {
 		r1, _, err := r.ReadRune()
 		if err != nil {
-			return expression{}, err
+			return expression{}, incomplete()
 		}
 		if unicode.IsSpace(r1) {
 			err = skipExpressionSpaces(r)
 			if err != nil {
-				return expression{}, err
+				return expression{}, incomplete()
 			}
 			break
 		}
@@ -296,15 +308,16 @@ func parseExpression(header []string, r *strings.Reader) (e expression, err erro
 
 	// get the operand
 	b.Reset()
+	opPos := pos()
 	for {
 		r1, _, err := r.ReadRune()
 		if err != nil {
-			return expression{}, err
+			return expression{}, incomplete()
 		}
 		if unicode.IsSpace(r1) {
 			err = skipExpressionSpaces(r)
 			if err != nil {
-				return expression{}, err
+				return expression{}, incomplete()
 			}
 			break
 		}
@@ -336,15 +349,18 @@ func parseExpression(header []string, r *strings.Reader) (e expression, err erro
 		op = opLess
 	case "<=":
 		op = opLessEqual
+	case "":
+		return expression{}, &exprError{opPos, "expecting an operand"}
 	default:
-		return expression{}, fmt.Errorf("unknown operand: %s", s)
+		return expression{}, &exprError{opPos, fmt.Sprintf("unknown operand %q", s)}
 	}
 
 	// reads the second operand
 	b.Reset()
+	valPos := pos()
 	r1, _, err := r.ReadRune()
 	if err != nil {
-		return expression{}, err
+		return expression{}, incomplete()
 	}
 	var val interface{}
 	var col2 string
@@ -381,7 +397,7 @@ func parseExpression(header []string, r *strings.Reader) (e expression, err erro
 		}
 		val, err = strconv.ParseFloat(b.String(), 64)
 		if err != nil {
-			return expression{}, err
+			return expression{}, &exprError{valPos, fmt.Sprintf("invalid number %q", b.String())}
 		}
 	} else {
 		// another column
@@ -405,22 +421,49 @@ func parseExpression(header []string, r *strings.Reader) (e expression, err erro
 		op:    op,
 		value: val,
 	}
-	for i, h := range header {
-		if h == col1 {
-			e.cols[0] = i
-		}
-		if h == col2 {
-			e.cols[1] = i
-		}
+	if e.cols[0], err = findColumn(header, col1); err != nil {
+		return expression{}, &exprError{0, err.Error()}
 	}
 	if e.cols[0] == -1 {
-		return expression{}, errors.New("expecting a valid column name")
+		return expression{}, &exprError{0, fmt.Sprintf("unknown column %q", col1)}
+	}
+	if len(col2) > 0 {
+		if e.cols[1], err = findColumn(header, col2); err != nil {
+			return expression{}, &exprError{valPos, err.Error()}
+		}
+		if e.cols[1] == -1 {
+			return expression{}, &exprError{valPos, fmt.Sprintf("unknown column %q", col2)}
+		}
 	}
 	return e, nil
 }
 
+// exprError is an error in an expression.
+type exprError struct {
+	pos int // position of the error, in bytes
+	msg string
+}
+
+func (e *exprError) Error() string {
+	return e.msg
+}
+
+// expressionError returns an error of an expression, showing the
+// expression with a caret under the position of the error.
+func expressionError(exp string, err error) error {
+	ee, ok := err.(*exprError)
+	if !ok {
+		return fmt.Errorf("invalid expression %q: %v", exp, err)
+	}
+	caret := strings.Repeat(" ", displayWidth(exp[:ee.pos])) + "^"
+	return fmt.Errorf("invalid expression: %s\n\t%s\n\t%s", ee.msg, exp, caret)
+}
+
 // getFieldValue returns the numeric or string value of a row field.
 func getFieldValue(field string) (value interface{}) {
+	if len(field) == 0 {
+		return field
+	}
 	r1 := []rune(field)[0]
 	if unicode.IsDigit(r1) || (r1 == '-') || (r1 == '.') {
 		var err error
@@ -178,3 +178,34 @@ func TestRowsSelect(t *testing.T) {
 		t.Errorf("Rows: expecting %d rows, found: %d", 3, i)
 	}
 }
+
+func TestExpressionError(t *testing.T) {
+	header := []string{"Item", "Cost", "Descripción"}
+	tests := []struct {
+		exp   string
+		pos   int
+		caret string
+	}{
+		{"Cost >> 5", 5, "     ^"},
+		{"Costs > 5", 0, "^"},
+		{"Cost > 5x", 7, "       ^"},
+		{"Cost <= Value", 8, "        ^"},
+		{"Descripción 5", 13, "            ^"},
+		{"Item", 4, "    ^"},
+	}
+	for _, ts := range tests {
+		_, err := parseExpression(header, strings.NewReader(ts.exp))
+		ee, ok := err.(*exprError)
+		if !ok {
+			t.Errorf("Rows: %q: expecting an expression error, found %v", ts.exp, err)
+			continue
+		}
+		if ee.pos != ts.pos {
+			t.Errorf("Rows: %q: expecting error at %d, found %d", ts.exp, ts.pos, ee.pos)
+		}
+		msg := expressionError(ts.exp, err).Error()
+		if !strings.HasSuffix(msg, "\n\t"+ts.exp+"\n\t"+ts.caret) {
+			t.Errorf("Rows: %q: expecting caret %q, found %q", ts.exp, ts.caret, msg)
+		}
+	}
+}
@@ -0,0 +1,277 @@
+// Copyright (c) 2016, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/js-arias/cmdapp"
+)
+
+var sampleCmd = &cmdapp.Command{
+	Run: sampleRun,
+	UsageLine: `sample [-f <char>] [--dups <policy>] [--encoding <name>]
+	[--from <format>] [-i|--input <file>] [-n|--no-header]
+	[-o|--output <file>] [--ragged <policy>] [--schema <file>]
+	[--to <format>] [-r|--rows <number>] [--fraction <number>]
+	[-g|--group <column>] [--replace] [--seed <number>]`,
+	Short: "outputs a random sample of the rows of a table",
+	Long: `
+Command sample outputs a table with a random sample of the rows of the
+input table. The rows are printed in the order of the input table.
+
+With -r or --rows, a sample of fixed size is taken, using reservoir
+sampling, so only the sampled rows are kept in memory. If the table has
+fewer rows, all the rows are printed. With --fraction, each row is
+selected with the given probability (a number between 0 and 1).
+
+With -g or --group, the sample is stratified by the values of the given
+column, i.e. a sample of the given size is taken for each value of the
+column. As rows sampled by fraction are selected independently, the
+group has no effect with --fraction.
+
+With --replace, the rows are sampled with replacement (as in a bootstrap),
+so a row can be printed several times. If no size is given, the size of
+the sample is the number of rows of the table (or of each group). To
+sample with replacement, all the rows are kept in memory.
+
+Options are:
+
+    -f <char>
+      Sets the field separation character. By default the value is the tab
+      character.
+
+    --dups <policy>
+      Sets how to handle duplicated column names in the header, one of
+      warn (the default), error, or rename. See 'tables help formats'.
+
+    --encoding <name>
+      Sets the character encoding of the input. By default the encoding
+      is detected from the input. See 'tables help formats' for the
+      available encodings.
+
+    --from <format>
+      Sets the format of the input table. By default the format is
+      detected from the extension of the input file, or it is a delimited
+      text table. See 'tables help formats' for the available formats.
+
+    -i <file>
+    --input <file>
+      Read the table from <file> instead of stdin.
+
+    -n
+    --no-header
+      If set, the table will be printed without a header.
+
+    -o <file>
+    --output <file>
+      Write the resulting table to <file> instead of stdout.
+
+    --ragged <policy>
+      Sets how to handle records with a different number of fields than
+      the header, one of error (the default), pad, truncate, or skip. See
+      'tables help formats'.
+
+    --schema <file>
+      Read the types of the columns of the input from a schema file. See
+      'tables help schema'.
+
+    --to <format>
+      Sets the format of the output table. By default the format is
+      detected from the extension of the output file, or it is a delimited
+      text table. See 'tables help formats' for the available formats.
+
+    -r <number>
+    --rows <number>
+      Sets the number of rows of the sample.
+
+    --fraction <number>
+      Sets the probability of selecting each row.
+
+    -g <column>
+    --group <column>
+      Takes a sample for each value of the given column.
+
+    --replace
+      If set, the rows are sampled with replacement.
+
+    --seed <number>
+      Sets the seed of the random number generator, so the same sample
+      can be taken again. By default the seed is taken from the clock.
+	`,
+}
+
+var sampleRows int         // set the size of the sample, -r|--rows
+var sampleFraction float64 // set the fraction of rows, --fraction
+var sampleGroup string     // set the group column, -g|--group
+var sampleReplace bool     // sample with replacement, --replace
+var sampleSeed int64       // set random seed, --seed
+
+func init() {
+	initCommonFlags(sampleCmd)
+	sampleCmd.Flag.IntVar(&sampleRows, "rows", -1, "")
+	sampleCmd.Flag.IntVar(&sampleRows, "r", -1, "")
+	sampleCmd.Flag.Float64Var(&sampleFraction, "fraction", -1, "")
+	sampleCmd.Flag.StringVar(&sampleGroup, "group", "", "")
+	sampleCmd.Flag.StringVar(&sampleGroup, "g", "", "")
+	sampleCmd.Flag.BoolVar(&sampleReplace, "replace", false, "")
+	sampleCmd.Flag.Int64Var(&sampleSeed, "seed", 0, "")
+}
+
+func sampleRun(c *cmdapp.Command, args []string) error {
+	seed := time.Now().UnixNano()
+	c.Flag.Visit(func(f *flag.Flag) {
+		if f.Name == "seed" {
+			seed = sampleSeed
+		}
+	})
+	s := &sampler{
+		n:       sampleRows,
+		replace: sampleReplace,
+		rng:     rand.New(rand.NewSource(seed)),
+	}
+	switch {
+	case sampleFraction >= 0 && (sampleRows >= 0 || sampleReplace):
+		return errors.New("option --fraction can not be used with --rows or --replace")
+	case sampleFraction > 1:
+		return errors.New("the fraction must be between 0 and 1")
+	case sampleFraction < 0 && sampleRows < 0 && !sampleReplace:
+		return errors.New("expecting the size of the sample, or a fraction")
+	}
+
+	r, err := openInput()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	w, err := openOutput()
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	header, err := r.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+	group := -1
+	if len(sampleGroup) > 0 {
+		if group, err = findColumn(header, sampleGroup); err != nil {
+			return err
+		}
+		if group < 0 {
+			return fmt.Errorf("unknown column %q", sampleGroup)
+		}
+	}
+	if err := w.Write(header); err != nil {
+		return err
+	}
+
+	for i := 0; ; i++ {
+		row, err := r.Read()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return err
+		}
+		if sampleFraction >= 0 {
+			if s.rng.Float64() < sampleFraction {
+				if err := w.Write(row); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		g := ""
+		if group >= 0 {
+			g = cell(row, group)
+		}
+		s.add(g, i, row)
+	}
+	for _, row := range s.rows() {
+		if err := w.Write(row); err != nil {
+			return err
+		}
+	}
+	return w.Close()
+}
+
+// sampleRow is a row of a sample.
+type sampleRow struct {
+	idx int // position in the table
+	rec []string
+}
+
+// sampler takes samples of a given size from the rows of a table.
+type sampler struct {
+	n       int  // size of the sample, if negative, the size of the group
+	replace bool // sample with replacement
+	rng     *rand.Rand
+	groups  map[string]*reservoir
+	order   []string // groups in the order in which they are found
+}
+
+// reservoir is the sample of a group.
+type reservoir struct {
+	seen int
+	rows []sampleRow
+}
+
+// add adds a row of a group.
+func (s *sampler) add(group string, idx int, rec []string) {
+	if s.groups == nil {
+		s.groups = make(map[string]*reservoir)
+	}
+	res, ok := s.groups[group]
+	if !ok {
+		res = &reservoir{}
+		s.groups[group] = res
+		s.order = append(s.order, group)
+	}
+	res.seen++
+	row := sampleRow{idx, rec}
+	if s.replace || len(res.rows) < s.n {
+		// with replacement all the rows are stored
+		res.rows = append(res.rows, row)
+		return
+	}
+	if j := s.rng.Intn(res.seen); j < s.n {
+		res.rows[j] = row
+	}
+}
+
+// rows returns the sampled rows in the order of the table.
+func (s *sampler) rows() [][]string {
+	var sample []sampleRow
+	for _, g := range s.order {
+		res := s.groups[g]
+		if !s.replace {
+			sample = append(sample, res.rows...)
+			continue
+		}
+		n := s.n
+		if n < 0 {
+			n = len(res.rows)
+		}
+		for i := 0; i < n; i++ {
+			sample = append(sample, res.rows[s.rng.Intn(len(res.rows))])
+		}
+	}
+	sort.SliceStable(sample, func(i, j int) bool { return sample[i].idx < sample[j].idx })
+	recs := make([][]string, len(sample))
+	for i, r := range sample {
+		recs[i] = r.rec
+	}
+	return recs
+}
@@ -0,0 +1,63 @@
+// Copyright (c) 2016, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+func TestSampler(t *testing.T) {
+	tests := []struct {
+		n       int
+		replace bool
+		size    int // size of each group
+	}{
+		{5, false, 5},
+		{200, false, 100},
+		{5, true, 5},
+		{-1, true, 100},
+	}
+	for _, ts := range tests {
+		var prev []string
+		for k := 0; k < 2; k++ {
+			s := &sampler{n: ts.n, replace: ts.replace, rng: rand.New(rand.NewSource(10))}
+			for i := 0; i < 200; i++ {
+				s.add(strconv.Itoa(i%2), i, []string{strconv.Itoa(i)})
+			}
+			rows := s.rows()
+			if len(rows) != 2*ts.size {
+				t.Errorf("Sample: n %d replace %v: expecting %d rows, found %d", ts.n, ts.replace, 2*ts.size, len(rows))
+				continue
+			}
+			groups := make(map[int]int)
+			last := -1
+			var ids []string
+			for _, r := range rows {
+				v, _ := strconv.Atoi(r[0])
+				if v < last || (!ts.replace && v == last) {
+					t.Errorf("Sample: n %d replace %v: rows out of order", ts.n, ts.replace)
+				}
+				last = v
+				groups[v%2]++
+				ids = append(ids, r[0])
+			}
+			if groups[0] != ts.size || groups[1] != ts.size {
+				t.Errorf("Sample: n %d replace %v: expecting %d rows per group, found %v", ts.n, ts.replace, ts.size, groups)
+			}
+			if k == 0 {
+				prev = ids
+				continue
+			}
+			for i := range ids {
+				if ids[i] != prev[i] {
+					t.Errorf("Sample: n %d replace %v: different samples with the same seed", ts.n, ts.replace)
+					break
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,573 @@
+// Copyright (c) 2016, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/js-arias/cmdapp"
+)
+
+var schemaCmd = &cmdapp.Command{
+	Run: schemaRun,
+	UsageLine: `schema [-f <char>] [--dups <policy>] [--encoding <name>]
+	[--from <format>] [-i|--input <file>] [-n|--no-header]
+	[-o|--output <file>] [--ragged <policy>] [--schema <file>]
+	[--to <format>] [<column>...]`,
+	Short: "infers the types of the columns",
+	Long: `
+Command schema reads a table, and outputs a table with a row for each of the
+indicated columns, with the following columns:
+
+    Column
+      The name of the column.
+
+    Type
+      The type of the column inferred from its values, one of integer,
+      float, boolean (true, false, yes, or no), date (as 2006-01-02,
+      2006/01/02, or 2006-01-02 15:04:05, optionally with a time zone),
+      or string. Empty fields are ignored.
+
+    Nulls
+      The number of empty fields.
+
+    Distinct
+      The number of distinct values. If there are more than 1024
+      distinct values, the number is an estimate.
+
+    Min
+    Max
+      The minimum and maximum values, compared as numbers in numeric
+      columns, as dates in date columns, and as text in other columns.
+
+    Examples
+      The first three distinct values, separated by semicolons.
+
+If no columns are indicated all the columns in the table will be reported.
+
+The output table can be used as a schema file in other commands, with the
+--schema option, so the values of each column are read with the type
+defined in the schema, instead of guessing the type of each value. Only the
+columns Column and Type are used to read the values (other columns are
+used by the validate command), so a schema file can be also written by
+hand, or edited from the output of this command.
+
+Integer and float columns are read as numbers, and other columns are read
+as text. A column with an empty type, or not found in the schema file, is
+read guessing the type of each value. A non empty value that is not of the
+type of its column is an error, except in the validate command, that
+reports it as a violation of the schema.
+
+Options are:
+
+    -f <char>
+      Sets the field separation character. By default the value is the tab
+      character.
+
+    --dups <policy>
+      Sets how to handle duplicated column names in the header, one of
+      warn (the default), error, or rename. See 'tables help formats'.
+
+    --encoding <name>
+      Sets the character encoding of the input. By default the encoding
+      is detected from the input. See 'tables help formats' for the
+      available encodings.
+
+    --from <format>
+      Sets the format of the input table. By default the format is
+      detected from the extension of the input file, or it is a delimited
+      text table. See 'tables help formats' for the available formats.
+
+    -i <file>
+    --input <file>
+      Read the table from <file> instead of stdin.
+
+    -n
+    --no-header
+      If set, the table will be printed without a header.
+
+    -o <file>
+    --output <file>
+      Write the resulting table to <file> instead of stdout.
+
+    --ragged <policy>
+      Sets how to handle records with a different number of fields than
+      the header, one of error (the default), pad, truncate, or skip. See
+      'tables help formats'.
+
+    --schema <file>
+      Read the types of the columns of the input from a schema file.
+      The types are only used to read the input, and do not change the
+      inferred types.
+
+    --to <format>
+      Sets the format of the output table. By default the format is
+      detected from the extension of the output file, or it is a delimited
+      text table. See 'tables help formats' for the available formats.
+
+    <column>
+      One or more columns, selected as in 'tables help cols'.
+	`,
+}
+
+func init() {
+	initCommonFlags(schemaCmd)
+}
+
+func schemaRun(c *cmdapp.Command, args []string) error {
+	r, err := openInput()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	w, err := openOutput()
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	cols, head, err := selectColumns(r, args)
+	if err != nil {
+		return err
+	}
+
+	st := make([]*schemaStats, len(head))
+	for i := range st {
+		st[i] = newSchemaStats()
+	}
+	for {
+		row, err := colsFn(r, head)
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return err
+		}
+		for i, v := range row {
+			st[i].add(v)
+		}
+	}
+
+	err = w.Write([]string{"Column", "Type", "Nulls", "Distinct", "Min", "Max", "Examples"})
+	if err != nil {
+		return err
+	}
+	for i, s := range st {
+		tp := s.kind()
+		min, max := s.minMax(tp)
+		row := []string{
+			cols[i],
+			tp,
+			strconv.Itoa(s.nulls),
+			strconv.Itoa(s.distinct.count()),
+			min,
+			max,
+			strings.Join(s.examples, "; "),
+		}
+		if err := w.Write(row); err != nil {
+			return err
+		}
+	}
+	return w.Close()
+}
+
+// Value types of a schema.
+const (
+	integerSchema = "integer"
+	floatSchema   = "float"
+	booleanSchema = "boolean"
+	dateSchema    = "date"
+	stringSchema  = "string"
+)
+
+// Bit sets of the types of a value.
+const (
+	integerBit = 1 << iota
+	floatBit
+	booleanBit
+	dateBit
+)
+
+// dateLayouts are the accepted date layouts.
+var dateLayouts = []string{
+	"2006-01-02",
+	"2006/01/02",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05Z07:00",
+}
+
+// parseDate returns the time of a date.
+func parseDate(v string) (time.Time, bool) {
+	for _, l := range dateLayouts {
+		if t, err := time.Parse(l, v); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// valueBits returns the types of a value, as a bit set.
+func valueBits(v string) int {
+	if _, ok := getFieldValue(v).(float64); ok {
+		if _, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return integerBit | floatBit
+		}
+		return floatBit
+	}
+	switch strings.ToLower(v) {
+	case "true", "false", "yes", "no":
+		return booleanBit
+	}
+	if _, ok := parseDate(v); ok {
+		return dateBit
+	}
+	return 0
+}
+
+// inList returns true if a value is in a list of values.
+func inList(list []string, v string) bool {
+	for _, l := range list {
+		if l == v {
+			return true
+		}
+	}
+	return false
+}
+
+// schemaStats stores the values used to infer the type of a column.
+type schemaStats struct {
+	bits     int // types of all the values
+	n        int // number of values
+	nulls    int
+	distinct distinctCounter
+	examples []string
+
+	// minimum and maximum values as numbers, dates, and text
+	minNum, maxNum   string
+	minDate, maxDate string
+	minText, maxText string
+}
+
+// newSchemaStats returns a new schemaStats.
+func newSchemaStats() *schemaStats {
+	return &schemaStats{bits: integerBit | floatBit | booleanBit | dateBit}
+}
+
+// add adds a value of a column.
+func (s *schemaStats) add(v string) {
+	if len(v) == 0 {
+		s.nulls++
+		return
+	}
+	if len(s.examples) < 3 && !inList(s.examples, v) {
+		s.examples = append(s.examples, v)
+	}
+	s.distinct.add(v)
+	bits := valueBits(v)
+	s.bits &= bits
+	first := s.n == 0
+	s.n++
+	if first || v < s.minText {
+		s.minText = v
+	}
+	if first || v > s.maxText {
+		s.maxText = v
+	}
+	if s.bits&floatBit != 0 {
+		x, _ := strconv.ParseFloat(v, 64)
+		if first || x < mustFloat(s.minNum) {
+			s.minNum = v
+		}
+		if first || x > mustFloat(s.maxNum) {
+			s.maxNum = v
+		}
+	}
+	if s.bits&dateBit != 0 {
+		t, _ := parseDate(v)
+		if mn, _ := parseDate(s.minDate); first || t.Before(mn) {
+			s.minDate = v
+		}
+		if mx, _ := parseDate(s.maxDate); first || t.After(mx) {
+			s.maxDate = v
+		}
+	}
+}
+
+// mustFloat returns the numeric value of a number.
+func mustFloat(v string) float64 {
+	x, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return math.NaN()
+	}
+	return x
+}
+
+// kind returns the inferred type of the column.
+func (s *schemaStats) kind() string {
+	switch {
+	case s.n == 0:
+		return stringSchema
+	case s.bits&integerBit != 0:
+		return integerSchema
+	case s.bits&floatBit != 0:
+		return floatSchema
+	case s.bits&booleanBit != 0:
+		return booleanSchema
+	case s.bits&dateBit != 0:
+		return dateSchema
+	}
+	return stringSchema
+}
+
+// minMax returns the minimum and maximum values of the column for a type.
+func (s *schemaStats) minMax(tp string) (string, string) {
+	switch tp {
+	case integerSchema, floatSchema:
+		return s.minNum, s.maxNum
+	case dateSchema:
+		return s.minDate, s.maxDate
+	}
+	return s.minText, s.maxText
+}
+
+// distinctK is the number of hashes stored to estimate the number of
+// distinct values.
+const distinctK = 1024
+
+// distinctCounter counts the number of distinct values. If there are more
+// than distinctK values, the number is estimated from the smallest
+// distinctK hashes of the values.
+type distinctCounter struct {
+	hashes []uint64 // sorted hashes
+}
+
+// add adds a value.
+func (d *distinctCounter) add(v string) {
+	h := fnv.New64a()
+	io.WriteString(h, v)
+	x := h.Sum64()
+	// mix the bits of the hash
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+
+	i := sort.Search(len(d.hashes), func(i int) bool { return d.hashes[i] >= x })
+	if i < len(d.hashes) && d.hashes[i] == x {
+		return
+	}
+	if len(d.hashes) == distinctK {
+		if i == distinctK {
+			return
+		}
+		d.hashes = d.hashes[:distinctK-1]
+	}
+	d.hashes = append(d.hashes, 0)
+	copy(d.hashes[i+1:], d.hashes[i:])
+	d.hashes[i] = x
+}
+
+// count returns the number of distinct values.
+func (d *distinctCounter) count() int {
+	if len(d.hashes) < distinctK {
+		return len(d.hashes)
+	}
+	return int(float64(distinctK-1) * math.MaxUint64 / float64(d.hashes[distinctK-1]))
+}
+
+// tableSchema is the definition of the columns of a table, read from a
+// schema file.
+type tableSchema struct {
+	cols  map[string]*schemaColumn
+	order []*schemaColumn // columns in the order of the schema file
+}
+
+// schemaColumn is the definition of a column in a schema file.
+type schemaColumn struct {
+	name     string
+	typ      string // type of the values, empty if unknown
+	required bool   // the column must be present, without empty values
+	key      bool   // the column is part of the key of the table
+	values   []string
+	pattern  string
+	re       *regexp.Regexp // compiled pattern
+	min, max string
+}
+
+// validType returns true if a non empty value is of the type of the
+// column.
+func (c *schemaColumn) validType(val string) bool {
+	bits := valueBits(val)
+	switch c.typ {
+	case integerSchema:
+		return bits&integerBit != 0
+	case floatSchema:
+		return bits&floatBit != 0
+	case booleanSchema:
+		return bits&booleanBit != 0
+	case dateSchema:
+		return bits&dateBit != 0
+	}
+	return true
+}
+
+// schemaCache stores the schema files already read.
+var schemaCache = make(map[string]*tableSchema)
+
+// readSchema reads a schema file. The schema file is a table, in any
+// format, with the columns Column and Type, and optionally the columns
+// used in validations (Required, Key, Values, Pattern, Min, and Max).
+func readSchema(name string) (*tableSchema, error) {
+	if s, ok := schemaCache[name]; ok {
+		return s, nil
+	}
+	t, err := openTableAs(name, "")
+	if err != nil {
+		return nil, err
+	}
+	defer t.Close()
+	header, err := t.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil, fmt.Errorf("schema %s: empty file", name)
+		}
+		return nil, err
+	}
+	fields := make(map[string]int)
+	for i, h := range header {
+		fields[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	if _, ok := fields["column"]; !ok {
+		return nil, fmt.Errorf("schema %s: column \"Column\" not found", name)
+	}
+	s := &tableSchema{cols: make(map[string]*schemaColumn)}
+	for ln := 2; ; ln++ {
+		rec, err := t.Read()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		field := func(f string) string {
+			i, ok := fields[f]
+			if !ok {
+				return ""
+			}
+			return strings.TrimSpace(cell(rec, i))
+		}
+		c := &schemaColumn{
+			name:     cell(rec, fields["column"]),
+			typ:      strings.ToLower(field("type")),
+			required: isTrue(field("required")),
+			key:      isTrue(field("key")),
+			min:      field("min"),
+			max:      field("max"),
+		}
+		switch c.typ {
+		case "", integerSchema, floatSchema, booleanSchema, dateSchema, stringSchema:
+		default:
+			return nil, fmt.Errorf("schema %s: row %d: unknown type %q", name, ln, c.typ)
+		}
+		if v := field("values"); len(v) > 0 {
+			for _, x := range strings.Split(v, ";") {
+				c.values = append(c.values, strings.TrimSpace(x))
+			}
+		}
+		if c.pattern = field("pattern"); len(c.pattern) > 0 {
+			if c.re, err = regexp.Compile("^(?:" + c.pattern + ")$"); err != nil {
+				return nil, fmt.Errorf("schema %s: row %d: %v", name, ln, err)
+			}
+		}
+		for _, v := range []string{c.min, c.max} {
+			if len(v) > 0 && c.compare(v, v) != 0 {
+				return nil, fmt.Errorf("schema %s: row %d: invalid range value %q for type %s", name, ln, v, c.typ)
+			}
+		}
+		if _, dup := s.cols[c.name]; dup {
+			return nil, fmt.Errorf("schema %s: row %d: column %q already defined", name, ln, c.name)
+		}
+		s.cols[c.name] = c
+		s.order = append(s.order, c)
+	}
+	schemaCache[name] = s
+	return s, nil
+}
+
+// isTrue returns true if a value of a schema file is a true value.
+func isTrue(v string) bool {
+	switch strings.ToLower(v) {
+	case "true", "yes", "y", "1":
+		return true
+	}
+	return false
+}
+
+// compare compares two values of a column, as dates in date columns, and
+// as numbers in numeric columns, or if both values are numbers. It returns
+// -1, 0, or 1, and 2 if the values can not be compared.
+func (c *schemaColumn) compare(a, b string) int {
+	switch {
+	case c.typ == dateSchema:
+		x, ok1 := parseDate(a)
+		y, ok2 := parseDate(b)
+		if !ok1 || !ok2 {
+			return 2
+		}
+		if x.Before(y) {
+			return -1
+		}
+		if x.After(y) {
+			return 1
+		}
+		return 0
+	case c.typ == integerSchema || c.typ == floatSchema || (valueBits(a)&valueBits(b)&floatBit != 0):
+		x, y := mustFloat(a), mustFloat(b)
+		if math.IsNaN(x) || math.IsNaN(y) {
+			return 2
+		}
+		if x < y {
+			return -1
+		}
+		if x > y {
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(a, b)
+}
+
+// types returns the types of the columns of a header. Columns not defined
+// in the schema, or without a type, use the types given by the format of
+// the table.
+func (s *tableSchema) types(header []string, def []colType) []colType {
+	types := make([]colType, len(header))
+	for i, h := range header {
+		if i < len(def) {
+			types[i] = def[i]
+		}
+		c, ok := s.cols[h]
+		if !ok {
+			continue
+		}
+		switch c.typ {
+		case integerSchema, floatSchema:
+			types[i] = numberType
+		case booleanSchema, dateSchema, stringSchema:
+			types[i] = stringType
+		}
+	}
+	return types
+}
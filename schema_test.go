@@ -0,0 +1,116 @@
+// Copyright (c) 2016, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestSchemaStats(t *testing.T) {
+	tests := []struct {
+		vals          []string
+		tp, min, max  string
+		nulls, distin int
+	}{
+		{[]string{"10", "", "9", "-2", "9"}, integerSchema, "-2", "10", 1, 3},
+		{[]string{"1.5", "10", "2e-3"}, floatSchema, "2e-3", "10", 0, 3},
+		{[]string{"yes", "No", ""}, booleanSchema, "No", "yes", 1, 2},
+		{[]string{"2020-01-02", "2019/12/31", "2020-01-01 10:00:00"}, dateSchema, "2019/12/31", "2020-01-02", 0, 3},
+		{[]string{"10", "b", "a"}, stringSchema, "10", "b", 0, 3},
+		{[]string{"", ""}, stringSchema, "", "", 2, 0},
+	}
+	for i, ts := range tests {
+		s := newSchemaStats()
+		for _, v := range ts.vals {
+			s.add(v)
+		}
+		tp := s.kind()
+		min, max := s.minMax(tp)
+		if tp != ts.tp || min != ts.min || max != ts.max {
+			t.Errorf("Schema: test %d: expecting %s [%s, %s], found %s [%s, %s]", i, ts.tp, ts.min, ts.max, tp, min, max)
+		}
+		if s.nulls != ts.nulls || s.distinct.count() != ts.distin {
+			t.Errorf("Schema: test %d: expecting %d nulls %d distinct, found %d %d", i, ts.nulls, ts.distin, s.nulls, s.distinct.count())
+		}
+	}
+}
+
+func TestDistinctCounter(t *testing.T) {
+	var d distinctCounter
+	for i := 0; i < 100000; i++ {
+		d.add(strconv.Itoa(i % 50000))
+	}
+	if n := d.count(); n < 45000 || n > 55000 {
+		t.Errorf("Schema: expecting about 50000 distinct values, found %d", n)
+	}
+}
+
+func TestReadSchema(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tables")
+	if err != nil {
+		t.Fatalf("Schema: unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "schema.tsv")
+	ioutil.WriteFile(name, []byte("Column\tType\tNulls\nItem\tinteger\t0\nCode\tstring\t0\nDate\t\t0\n"), 0644)
+	s, err := readSchema(name)
+	if err != nil {
+		t.Fatalf("Schema: unexpected error: %v", err)
+	}
+	types := s.types([]string{"Code", "Item", "Date", "Other"}, []colType{anyType, stringType, anyType, numberType})
+	x := []colType{stringType, numberType, anyType, numberType}
+	for i, tp := range x {
+		if types[i] != tp {
+			t.Errorf("Schema: expecting type %d for column %d, found %d", tp, i, types[i])
+		}
+	}
+
+	bad := filepath.Join(dir, "bad.tsv")
+	ioutil.WriteFile(bad, []byte("Column\tType\nItem\tnumeric\n"), 0644)
+	if _, err := readSchema(bad); err == nil {
+		t.Errorf("Schema: expecting error on unknown type")
+	}
+}
+
+func TestSchemaTypedRead(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tables")
+	if err != nil {
+		t.Fatalf("Schema: unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "schema.tsv")
+	ioutil.WriteFile(name, []byte("Column\tType\nA\tstring\nB\tinteger\n"), 0644)
+	s, err := readSchema(name)
+	if err != nil {
+		t.Fatalf("Schema: unexpected error: %v", err)
+	}
+	recs := [][]string{{"A", "B"}, {"x", "1"}, {"y", ""}, {"foo", "bar"}}
+
+	tb := &inTable{r: &memReader{recs: recs}, schema: s, name: "s.tsv"}
+	for i := 0; i < 3; i++ {
+		if _, err := tb.Read(); err != nil {
+			t.Fatalf("Schema: record %d: unexpected error: %v", i+1, err)
+		}
+	}
+	_, err = tb.Read()
+	re, ok := err.(*readError)
+	if !ok {
+		t.Fatalf("Schema: expecting a read error, found %v", err)
+	}
+	if re.file != "s.tsv" || re.pos != "record 4" || re.column != "B" {
+		t.Errorf("Schema: unexpected error %v", err)
+	}
+
+	tb = &inTable{r: &memReader{recs: recs}, schema: s, name: "s.tsv", untyped: true}
+	for i := range recs {
+		if _, err := tb.Read(); err != nil {
+			t.Errorf("Schema: untyped: record %d: unexpected error: %v", i+1, err)
+		}
+	}
+}
@@ -0,0 +1,397 @@
+// Copyright (c) 2016, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/js-arias/cmdapp"
+)
+
+var splitCmd = &cmdapp.Command{
+	Run: splitRun,
+	UsageLine: `split [-f <char>] [--dups <policy>] [--encoding <name>]
+	[--from <format>] [-i|--input <file>] [-n|--no-header]
+	[-o|--output <template>] [--ragged <policy>] [--schema <file>]
+	[--to <format>] [--by <column>] [--chunk <number>]
+	[--max-open <number>]`,
+	Short: "splits a table into several files",
+	Long: `
+Command split writes the rows of a table into several tables, each one with
+the header of the input table. With --by, a table is written for each value
+of the given column, and with --chunk, a table is written for each group of
+the given number of rows. The order of the rows is the order of the input
+table.
+
+The names of the files are built from the template set with -o, replacing
+{} with the value of the column (or the number of the chunk, starting at
+1). By default, the template is "{}.tsv". The values are sanitized, i.e.
+path separators, spaces, and characters not allowed in file names are
+replaced by underscores, a starting dot is replaced by an underscore, and
+empty values are written as an underscore. Names of devices in Windows
+(e.g. CON, or NUL) are followed by an underscore. If two values produce the
+same name, ignoring the case, a number is added to the name of the second
+value (e.g. a_b_2).
+The format of each table is detected from the extension of the template,
+or it is set with --to.
+
+At most the number of files set with --max-open are kept open at the same
+time. If the column has more values, the rows of the values without an
+open file are stored in a temporary file, that is read again when the
+files of the first values are completed.
+
+Options are:
+
+    -f <char>
+      Sets the field separation character. By default the value is the tab
+      character.
+
+    --dups <policy>
+      Sets how to handle duplicated column names in the header, one of
+      warn (the default), error, or rename. See 'tables help formats'.
+
+    --encoding <name>
+      Sets the character encoding of the input. By default the encoding
+      is detected from the input. See 'tables help formats' for the
+      available encodings.
+
+    --from <format>
+      Sets the format of the input table. By default the format is
+      detected from the extension of the input file, or it is a delimited
+      text table. See 'tables help formats' for the available formats.
+
+    -i <file>
+    --input <file>
+      Read the table from <file> instead of stdin.
+
+    -n
+    --no-header
+      If set, the tables will be printed without a header.
+
+    -o <template>
+    --output <template>
+      Sets the template of the names of the output files.
+
+    --ragged <policy>
+      Sets how to handle records with a different number of fields than
+      the header, one of error (the default), pad, truncate, or skip. See
+      'tables help formats'.
+
+    --schema <file>
+      Read the types of the columns of the input from a schema file. See
+      'tables help schema'.
+
+    --to <format>
+      Sets the format of the output tables. By default the format is
+      detected from the extension of the template, or it is a delimited
+      text table. See 'tables help formats' for the available formats.
+
+    --by <column>
+      Writes a table for each value of the column.
+
+    --chunk <number>
+      Writes a table for each group of the given number of rows.
+
+    --max-open <number>
+      Sets the maximum number of open files. The default is 100.
+	`,
+}
+
+var splitBy string   // set the column of the values, --by
+var splitChunk int   // set the number of rows of each table, --chunk
+var splitMaxOpen int // set the maximum number of open files, --max-open
+
+func init() {
+	initCommonFlags(splitCmd)
+	splitCmd.Flag.StringVar(&splitBy, "by", "", "")
+	splitCmd.Flag.IntVar(&splitChunk, "chunk", 0, "")
+	splitCmd.Flag.IntVar(&splitMaxOpen, "max-open", 100, "")
+}
+
+func splitRun(c *cmdapp.Command, args []string) error {
+	switch {
+	case len(splitBy) == 0 && splitChunk == 0:
+		return errors.New("expecting a column (--by) or a number of rows (--chunk)")
+	case len(splitBy) > 0 && splitChunk != 0:
+		return errors.New("options --by and --chunk can not be used together")
+	case splitChunk < 0:
+		return errors.New("the number of rows must be positive")
+	case splitMaxOpen < 1:
+		return errors.New("the number of open files must be positive")
+	}
+	tmpl := output
+	if len(tmpl) == 0 {
+		tmpl = "{}.tsv"
+	}
+	if !strings.Contains(tmpl, "{}") {
+		return fmt.Errorf("output template %q without {}", tmpl)
+	}
+
+	r, err := openInput()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	header, err := r.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+	s := newSplitter(tmpl, header)
+	if splitChunk > 0 {
+		return s.chunks(r, splitChunk)
+	}
+	key, err := findColumn(header, splitBy)
+	if err != nil {
+		return err
+	}
+	if key < 0 {
+		return fmt.Errorf("unknown column %q", splitBy)
+	}
+	return s.byValue(r, key, splitMaxOpen)
+}
+
+// splitter writes the rows of a table into several tables.
+type splitter struct {
+	tmpl   string
+	header []string
+	names  map[string]string // file name of each value
+	used   map[string]bool   // sanitized values already used, in lower case
+}
+
+// newSplitter returns a new splitter.
+func newSplitter(tmpl string, header []string) *splitter {
+	return &splitter{
+		tmpl:   tmpl,
+		header: header,
+		names:  make(map[string]string),
+		used:   make(map[string]bool),
+	}
+}
+
+// create creates the table of a value.
+func (s *splitter) create(val string) (*outTable, error) {
+	t, err := createTable(s.name(val))
+	if err != nil {
+		return nil, err
+	}
+	if err := t.Write(s.header); err != nil {
+		t.Close()
+		return nil, err
+	}
+	return t, nil
+}
+
+// name returns the file name of a value.
+func (s *splitter) name(val string) string {
+	if nm, ok := s.names[val]; ok {
+		return nm
+	}
+	base := sanitizeName(val)
+	nm := base
+	// file names can be case insensitive
+	for i := 2; s.used[strings.ToLower(nm)]; i++ {
+		nm = base + "_" + strconv.Itoa(i)
+	}
+	s.used[strings.ToLower(nm)] = true
+	s.names[val] = strings.Replace(s.tmpl, "{}", nm, -1)
+	return s.names[val]
+}
+
+// chunks writes a table for each group of n rows.
+func (s *splitter) chunks(r recordReader, n int) error {
+	var t *outTable
+	defer func() {
+		if t != nil {
+			t.Close()
+		}
+	}()
+	for i := 0; ; i++ {
+		row, err := r.Read()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return err
+		}
+		if i%n == 0 {
+			if t != nil {
+				if err := t.Close(); err != nil {
+					return err
+				}
+			}
+			if t, err = s.create(strconv.Itoa(i/n + 1)); err != nil {
+				return err
+			}
+		}
+		if err := t.Write(row); err != nil {
+			return err
+		}
+	}
+	if t == nil {
+		return nil
+	}
+	return t.Close()
+}
+
+// byValue writes a table for each value of a column, with at most max
+// open tables. Rows of values without an open table are stored in a
+// temporary file, which is read in a new pass.
+func (s *splitter) byValue(r recordReader, key, max int) error {
+	read := r.Read
+	var prev *os.File
+	removePrev := func() {
+		if prev != nil {
+			prev.Close()
+			os.Remove(prev.Name())
+			prev = nil
+		}
+	}
+	defer removePrev()
+	for {
+		spill, err := s.pass(read, key, max)
+		removePrev()
+		if err != nil {
+			return err
+		}
+		if spill == nil {
+			return nil
+		}
+		prev = spill
+		if _, err := spill.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		dec := gob.NewDecoder(bufio.NewReader(spill))
+		read = func() ([]string, error) {
+			var rec []string
+			if err := dec.Decode(&rec); err != nil {
+				return nil, err
+			}
+			return rec, nil
+		}
+	}
+}
+
+// pass writes the rows of the first max values found, and returns the
+// temporary file with the other rows, or nil if all the rows were
+// written.
+func (s *splitter) pass(read func() ([]string, error), key, max int) (*os.File, error) {
+	open := make(map[string]*outTable)
+	defer func() {
+		for _, t := range open {
+			t.Close()
+		}
+	}()
+	var spill *os.File
+	var bw *bufio.Writer
+	var enc *gob.Encoder
+	fail := func(err error) (*os.File, error) {
+		if spill != nil {
+			spill.Close()
+			os.Remove(spill.Name())
+		}
+		return nil, err
+	}
+	for {
+		row, err := read()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return fail(err)
+		}
+		val := cell(row, key)
+		t, ok := open[val]
+		if !ok && len(open) < max {
+			if t, err = s.create(val); err != nil {
+				return fail(err)
+			}
+			open[val] = t
+			ok = true
+		}
+		if ok {
+			if err := t.Write(row); err != nil {
+				return fail(err)
+			}
+			continue
+		}
+		if spill == nil {
+			if spill, err = ioutil.TempFile("", "tables-split"); err != nil {
+				return nil, err
+			}
+			bw = bufio.NewWriter(spill)
+			enc = gob.NewEncoder(bw)
+		}
+		if err := enc.Encode(row); err != nil {
+			return fail(err)
+		}
+	}
+	for v, t := range open {
+		delete(open, v)
+		if err := t.Close(); err != nil {
+			return fail(err)
+		}
+	}
+	if spill != nil {
+		if err := bw.Flush(); err != nil {
+			return fail(err)
+		}
+	}
+	return spill, nil
+}
+
+// maxNameLen is the maximum length, in bytes, of a sanitized value.
+const maxNameLen = 200
+
+// sanitizeName returns a value that can be used as part of a file name.
+func sanitizeName(v string) string {
+	var b strings.Builder
+	for _, r := range v {
+		if unicode.IsSpace(r) || unicode.IsControl(r) || strings.ContainsRune(`/\:*?"<>|`, r) {
+			r = '_'
+		}
+		if b.Len()+utf8.RuneLen(r) > maxNameLen {
+			break
+		}
+		b.WriteRune(r)
+	}
+	s := b.String()
+	if len(s) == 0 {
+		return "_"
+	}
+	if s[0] == '.' {
+		s = "_" + s[1:]
+	}
+	base := s
+	if i := strings.Index(s, "."); i >= 0 {
+		base = s[:i]
+	}
+	if reservedNames[strings.ToUpper(base)] {
+		s = base + "_" + s[len(base):]
+	}
+	return s
+}
+
+// reservedNames are the names of devices in Windows, that can not be used
+// as file names, even with an extension.
+var reservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true,
+	"COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true,
+	"LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
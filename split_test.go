@@ -0,0 +1,98 @@
+// Copyright (c) 2016, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tables")
+	if err != nil {
+		t.Fatalf("Split: unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	header := []string{"Site", "Count"}
+	recs := [][]string{
+		[]string{"A", "1"},
+		[]string{"B/C", "2"},
+		[]string{"B_C", "3"},
+		[]string{"A", "4"},
+		[]string{"", "5"},
+		[]string{"B/C", "6"},
+	}
+	files := map[string]string{
+		"A":     "Site\tCount\r\nA\t1\r\nA\t4\r\n",
+		"B_C":   "Site\tCount\r\nB/C\t2\r\nB/C\t6\r\n",
+		"B_C_2": "Site\tCount\r\nB_C\t3\r\n",
+		"_":     "Site\tCount\r\n\t5\r\n",
+	}
+	read := func(name string) string {
+		b, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Errorf("Split: unexpected error: %v", err)
+		}
+		return string(b)
+	}
+	for _, max := range []int{1, 2, 10} {
+		s := newSplitter(filepath.Join(dir, "max"+strconv.Itoa(max)+"-{}.tsv"), header)
+		if err := s.byValue(&memReader{recs: append([][]string{}, recs...)}, 0, max); err != nil {
+			t.Errorf("Split: max open %d: unexpected error: %v", max, err)
+			continue
+		}
+		for nm, x := range files {
+			if got := read("max" + strconv.Itoa(max) + "-" + nm + ".tsv"); got != x {
+				t.Errorf("Split: max open %d: file %q: expecting %q, found %q", max, nm, x, got)
+			}
+		}
+	}
+
+	s := newSplitter(filepath.Join(dir, "chunk-{}.tsv"), header)
+	if err := s.chunks(&memReader{recs: append([][]string{}, recs...)}, 4); err != nil {
+		t.Errorf("Split: unexpected error: %v", err)
+	}
+	if got := read("chunk-2.tsv"); got != "Site\tCount\r\n\t5\r\nB/C\t6\r\n" {
+		t.Errorf("Split: unexpected chunk %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "chunk-3.tsv")); err == nil {
+		t.Errorf("Split: unexpected chunk 3")
+	}
+}
+
+func TestSanitizeName(t *testing.T) {
+	tests := map[string]string{
+		"Tucumán":                "Tucumán",
+		"../etc/passwd":          "_._etc_passwd",
+		".hidden":                "_hidden",
+		"a b\tc":                 "a_b_c",
+		`x:y*z?"<>|\`:            "x_y_z______",
+		"":                       "_",
+		strings.Repeat("ñ", 150): strings.Repeat("ñ", 100),
+		"con":                    "con_",
+		"Nul.txt":                "Nul_.txt",
+		"COM10":                  "COM10",
+	}
+	for v, x := range tests {
+		if s := sanitizeName(v); s != x {
+			t.Errorf("Sanitize: %q: expecting %q, found %q", v, x, s)
+		}
+	}
+
+	sp := newSplitter("{}.tsv", nil)
+	for _, v := range []string{"A", "a b", "a", "a_b", "A"} {
+		sp.name(v)
+	}
+	names := map[string]string{"A": "A.tsv", "a": "a_2.tsv", "a b": "a_b.tsv", "a_b": "a_b_2.tsv"}
+	for v, x := range names {
+		if nm := sp.name(v); nm != x {
+			t.Errorf("Sanitize: name %q: expecting %q, found %q", v, x, nm)
+		}
+	}
+}
@@ -5,10 +5,8 @@
 package main
 
 import (
-	"encoding/csv"
 	"io"
 	"math"
-	"os"
 	"strconv"
 
 	"github.com/js-arias/cmdapp"
@@ -16,8 +14,10 @@ import (
 
 var statsCmd = &cmdapp.Command{
 	Run: statsRun,
-	UsageLine: `stats [-f <char>] [-i|--input <file>] [-o|--output <file>]
-	[-p <number>] [-z|--empty-as-zero] <column>...`,
+	UsageLine: `stats [-f <char>] [--dups <policy>] [--encoding <name>]
+	[--from <format>] [-i|--input <file>] [-o|--output <file>]
+	[--ragged <policy>] [--schema <file>] [--to <format>] [-p <number>]
+	[-z|--empty-as-zero] <column>...`,
 	Short: "calculate basic stats of columns",
 	Long: `
 Command stats reads an input table and prints on the standard output a new
@@ -33,6 +33,20 @@ Options are:
       Sets the field separation charachter. By default the value is the tab
       character.
 
+    --dups <policy>
+      Sets how to handle duplicated column names in the header, one of
+      warn (the default), error, or rename. See 'tables help formats'.
+
+    --encoding <name>
+      Sets the character encoding of the input. By default the encoding
+      is detected from the input. See 'tables help formats' for the
+      available encodings.
+
+    --from <format>
+      Sets the format of the input table. By default the format is
+      detected from the extension of the input file, or it is a delimited
+      text table. See 'tables help formats' for the available formats.
+
     -i <file>
     --input <file>
       Read the table from <file> instead of stdin.
@@ -41,6 +55,20 @@ Options are:
     --output <file>
       Write the resulting table to <file> instead of stdout. 
 
+    --ragged <policy>
+      Sets how to handle records with a different number of fields than
+      the header, one of error (the default), pad, truncate, or skip. See
+      'tables help formats'.
+
+    --schema <file>
+      Read the types of the columns of the input from a schema file. See
+      'tables help schema'.
+
+    --to <format>
+      Sets the format of the output table. By default the format is
+      detected from the extension of the output file, or it is a delimited
+      text table. See 'tables help formats' for the available formats.
+
     -p <number>
       Sets the precision in number of decimals. The default is 3.
 
@@ -50,7 +78,7 @@ Options are:
       having a zero. Otherwise, they will be ignored.
 
     <column>
-      One or more column names.
+      One or more columns, selected as in 'tables help cols'.
 	`,
 }
 
@@ -65,34 +93,16 @@ func init() {
 }
 
 func statsRun(c *cmdapp.Command, args []string) error {
-	in := os.Stdin
-	if len(input) > 0 {
-		var err error
-		in, err = os.Open(input)
-		if err != nil {
-			return err
-		}
-		defer in.Close()
+	r, err := openInput()
+	if err != nil {
+		return err
 	}
-	out := os.Stdout
-	if len(output) > 0 {
-		var err error
-		out, err = os.Create(output)
-		if err != nil {
-			return err
-		}
-		defer out.Close()
-	}
-	if len(delim) == 0 {
-		delim = "\t"
-	}
-	r1 := []rune(delim)[0]
-	r := csv.NewReader(in)
-	r.Comma = r1
-	w := csv.NewWriter(out)
-	w.Comma = r1
-	w.UseCRLF = true
-	defer w.Flush()
+	defer r.Close()
+	w, err := openOutput()
+	if err != nil {
+		return err
+	}
+	defer w.Close()
 	cols, head, err := selectColumns(r, args)
 	if err != nil {
 		return err
@@ -217,7 +227,7 @@ func statsRun(c *cmdapp.Command, args []string) error {
 		return err
 	}
 
-	return nil
+	return w.Close()
 }
 
 // statsCalc contains the variables to calculate basic stats.
@@ -233,7 +243,7 @@ type statsCalc struct {
 // statsFn returns the numeric values of a set of columns (defined by head) in
 // a table. If no value is found, a zero will be returned and the
 // corresponding ok value as false.
-func statsFn(r *csv.Reader, head []int) (row []float64, oks []bool, err error) {
+func statsFn(r recordReader, head []int) (row []float64, oks []bool, err error) {
 	nr, err := r.Read()
 	if err != nil {
 		return nil, nil, err
@@ -244,7 +254,7 @@ func statsFn(r *csv.Reader, head []int) (row []float64, oks []bool, err error) {
 		if h == -1 {
 			continue
 		}
-		v, err := strconv.ParseFloat(nr[h], 64)
+		v, err := strconv.ParseFloat(cell(nr, h), 64)
 		if err != nil {
 			continue
 		}
@@ -31,7 +31,7 @@ func TestStats(t *testing.T) {
 			continue
 		}
 		if row[0] != 50 {
-			t.Errorf("Stats: expecting %.3f found %.3f", 50, row[0])
+			t.Errorf("Stats: expecting %.3f found %.3f", 50.0, row[0])
 		}
 		if oks[2] {
 			t.Errorf("Stats: column %d should be false", 2)
@@ -0,0 +1,912 @@
+// Copyright (c) 2016, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/js-arias/cmdapp"
+)
+
+var formatsHelp = &cmdapp.Command{
+	UsageLine: "formats",
+	Short:     "table formats",
+	Long: `
+Commands read and write tables in different formats. The format of the input
+table is set with the --from flag, and the format of the output table with
+the --to flag. If no format is given, the format is detected from the
+extension of the input or output file, and if the extension is not known,
+the table is a delimited text table. Some formats accept a parameter, that
+is given after a colon (e.g. --to html:results).
+
+Files compressed with gzip (.gz), bzip2 (.bz2) or zstd (.zst) are
+decompressed when read, and compressed when written, and the format of the
+table is detected from the extension before the compression extension (e.g.
+data.tsv.gz). Compressed input without a compression extension (e.g. from
+the standard input) is detected from its content. Writing bzip2 files, and reading or
+writing zstd files, requires the bzip2 and zstd programs.
+
+Text tables (i.e. all the formats, except xlsx, ods, parquet and arrow) are
+read in the encoding set with the --encoding flag. By default the encoding
+is detected from the byte order mark (for UTF-8 and UTF-16), and if there
+is no mark, and the start of the table is not valid UTF-8, the table is
+read as windows-1252. The encodings are: utf-8, utf-16 (big endian, unless
+it starts with a byte order mark), utf-16le, utf-16be, latin1 (or
+iso-8859-1), latin9 (or iso-8859-15), and windows-1252 (or cp1252). A byte
+order mark at the start of the table is always removed.
+
+Records with a different number of fields than the header (ragged rows)
+are handled with the policy set with the --ragged flag:
+
+    error
+      The default. The command fails, reporting the line (or the record
+      number, in formats without lines) of the record.
+
+    pad
+      Records with fewer fields are filled with empty fields. Records with
+      more fields are an error.
+
+    truncate
+      Records with fewer fields are filled with empty fields, and the
+      extra fields of records with more fields are removed.
+
+    skip
+      Ragged records are ignored, and a warning with the line of the
+      record is printed on the standard error.
+
+Duplicated column names in the header are handled with the policy set with
+the --dups flag:
+
+    warn
+      The default. A warning with the duplicated names is printed on the
+      standard error, and the header is not modified.
+
+    error
+      The command fails.
+
+    rename
+      The second and later columns with the same name are renamed by
+      adding a number to the name (e.g. Value, Value_2, Value_3).
+
+When a duplicated name is used in a command to select a column, the
+command fails, as the name is ambiguous. In that case, or in any case, a
+column can be indicated by its position in the header, starting at 1,
+with #<number> (e.g. #3 for the third column). If a column has that
+name, the name is used instead of the position.
+
+The formats are:
+
+    text
+      A delimited text table. Each record is a line, and fields are
+      separated by the character set with -f (by default the tab
+      character). The first line is the header.
+
+    rdb
+      A delimited text table with an /RDB header, i.e. the line with the
+      column names is followed by a line with the column definitions.
+      Each definition is made of an optional width, and an optional type
+      (N for numbers, S for strings), surrounded by any number of dashes
+      (e.g. "5N", "10S", "-----"). On reading, the types are used to
+      compare values (e.g. in rows command), if a column has no type, the
+      type is guessed from each value. On writing, the widths and types
+      are inferred from the data. Extension: .rdb.
+
+    json
+      An array of JSON objects, one object per row, keyed by the names of
+      the header. Numeric fields are written as JSON numbers, and empty
+      fields as null. On reading, nested objects are flattened joining the
+      keys with a dot (e.g. "location.lat"), and the header is the union of
+      the keys of all the objects. Extension: .json.
+
+    ndjson
+      As json, but with one object per line, and without the enclosing
+      array. Extensions: .ndjson, .jsonl.
+
+    fixed
+    fixed:<lines>
+    fixed:<column>:<start>-<end>,...
+      Input only. A table with fixed-width columns. By default, columns
+      are detected from the gutters (positions that are blank in all the
+      lines) found in the first 100 lines, or in the number of lines
+      given as parameter. In that case the first line is the header. The
+      parameter can also be a list of columns separated by commas, each
+      one with the column name, and the first and last character
+      positions of the column (starting at 1, e.g.
+      "Name:1-10,Lat:11-18"). If the last position is omitted, the
+      column extends to the end of the line. In that case all the lines
+      are data. Blank lines are ignored.
+
+    xlsx
+    xlsx:<sheet>
+      An Excel workbook. On reading, the parameter is the name, or the
+      index (starting at 1), of the sheet to read, by default the first
+      sheet is read, and the first row is the header. On writing, the
+      table is written in a single sheet, named after the parameter
+      (by default "Sheet1"), and numeric values are written as numbers.
+      Dates are read as the numbers stored in the workbook. Extension:
+      .xlsx.
+
+    ods
+    ods:<sheet>
+      An OpenDocument spreadsheet (as used by LibreOffice). The parameter
+      and the values are the same as in the xlsx format. Empty rows are
+      ignored. Extension: .ods.
+
+    parquet
+      An Apache Parquet file. On writing, the type of each column is
+      inferred from the data (64 bit integers, doubles, or strings), empty
+      fields are written as nulls, and the file is not compressed. On
+      reading, flat files with plain, dictionary, or RLE encoded pages,
+      either uncompressed, or compressed with snappy or gzip, are
+      supported. Extension: .parquet.
+
+    arrow
+      An Apache Arrow IPC stream, with the same column types as parquet.
+      On reading, Arrow IPC files are also accepted, but dictionary
+      encoded or compressed batches are not supported. Extensions: .arrow,
+      .arrows.
+
+    pretty
+      Output only. The table is written with aligned columns and
+      box-drawing borders, and numbers aligned to the right, for viewing
+      it on a terminal. Cells wider than 40 characters are truncated. If
+      the output is a terminal, and the PAGER environment variable is
+      set, the table is shown with that pager.
+
+    markdown
+      Output only. A GitHub flavoured Markdown table. Extension: .md.
+
+    html
+    html:<class>
+      Output only. An HTML table, if a class is given, it will be used as
+      the CSS class of the table. Extensions: .html, .htm.
+
+    latex
+      Output only. A LaTeX tabular environment, with rules made with
+      \hline. Extension: .tex.
+
+    booktabs
+      Output only. As latex, but using the rules of the booktabs package.
+
+In markdown, html, latex and booktabs formats, columns in which all the
+values are numbers are aligned to the right.
+	`,
+}
+
+// recordReader is the interface that wraps the Read method of a table
+// reader. The first record returned is the table header. When there are no
+// more records, Read returns io.EOF.
+type recordReader interface {
+	Read() (record []string, err error)
+}
+
+// recordWriter is the interface implemented by table writers. The first
+// record written is the table header. Flush must be called after the last
+// record is written.
+type recordWriter interface {
+	Write(record []string) error
+	Flush() error
+}
+
+// rowWriter is the interface that wraps the Write method of a table
+// writer, as recordWriter and outTable.
+type rowWriter interface {
+	Write(record []string) error
+}
+
+// tableFormat is a table format that can be read or written by the
+// commands.
+type tableFormat struct {
+	name   string
+	ext    []string // file extensions of the format
+	keyed  bool     // the header is always written
+	binary bool     // the format is not a text format
+
+	// newReader returns a reader of the format, nil if the format can
+	// not be read. Param is the format parameter.
+	newReader func(in io.Reader, param string) (recordReader, error)
+
+	// newWriter returns a writer of the format, nil if the format can
+	// not be written. Param is the format parameter.
+	newWriter func(out io.Writer, param string) recordWriter
+}
+
+// tableFormats are the supported table formats, the first format is the
+// default.
+var tableFormats = []*tableFormat{
+	&tableFormat{
+		name:      "text",
+		newReader: newTextReader,
+		newWriter: newTextWriter,
+	},
+	&tableFormat{
+		name:      "rdb",
+		ext:       []string{".rdb"},
+		keyed:     true,
+		newReader: newRDBReader,
+		newWriter: newRDBWriter,
+	},
+	&tableFormat{
+		name:      "json",
+		ext:       []string{".json"},
+		keyed:     true,
+		newReader: newJSONReader,
+		newWriter: newJSONWriter,
+	},
+	&tableFormat{
+		name:      "ndjson",
+		ext:       []string{".ndjson", ".jsonl"},
+		keyed:     true,
+		newReader: newJSONReader,
+		newWriter: newNDJSONWriter,
+	},
+	&tableFormat{
+		name:      "fixed",
+		newReader: newFixedReader,
+	},
+	&tableFormat{
+		name:      "xlsx",
+		ext:       []string{".xlsx"},
+		binary:    true,
+		newReader: newXLSXReader,
+		newWriter: newXLSXWriter,
+	},
+	&tableFormat{
+		name:      "ods",
+		ext:       []string{".ods"},
+		binary:    true,
+		newReader: newODSReader,
+		newWriter: newODSWriter,
+	},
+	&tableFormat{
+		name:      "parquet",
+		ext:       []string{".parquet"},
+		keyed:     true,
+		binary:    true,
+		newReader: newParquetReader,
+		newWriter: newParquetWriter,
+	},
+	&tableFormat{
+		name:      "arrow",
+		ext:       []string{".arrow", ".arrows"},
+		keyed:     true,
+		binary:    true,
+		newReader: newArrowReader,
+		newWriter: newArrowWriter,
+	},
+	&tableFormat{
+		name:      "pretty",
+		newWriter: newPrettyWriter,
+	},
+	&tableFormat{
+		name:      "markdown",
+		ext:       []string{".md"},
+		keyed:     true,
+		newWriter: newMarkdownWriter,
+	},
+	&tableFormat{
+		name:      "html",
+		ext:       []string{".html", ".htm"},
+		newWriter: newHTMLWriter,
+	},
+	&tableFormat{
+		name:      "latex",
+		ext:       []string{".tex"},
+		newWriter: newLaTeXWriter,
+	},
+	&tableFormat{
+		name:      "booktabs",
+		newWriter: newBooktabsWriter,
+	},
+}
+
+// getFormat returns the format indicated by name, or if name is empty, the
+// format of the file based on its extension. A format name can include a
+// parameter after a colon (e.g. "html:results"), which is also returned.
+func getFormat(name, file string) (tf *tableFormat, param string, err error) {
+	if len(name) > 0 {
+		if i := strings.Index(name, ":"); i >= 0 {
+			name, param = name[:i], name[i+1:]
+		}
+		for _, tf := range tableFormats {
+			if tf.name == name {
+				return tf, param, nil
+			}
+		}
+		return nil, "", fmt.Errorf("unknown table format: %s", name)
+	}
+	ext := strings.ToLower(filepath.Ext(file))
+	if len(ext) > 0 {
+		for _, tf := range tableFormats {
+			for _, e := range tf.ext {
+				if e == ext {
+					return tf, "", nil
+				}
+			}
+		}
+	}
+	return tableFormats[0], "", nil
+}
+
+// delimRune returns the field delimiter.
+func delimRune() rune {
+	if len(delim) == 0 {
+		delim = "\t"
+	}
+	return []rune(delim)[0]
+}
+
+// newTextReader returns a reader for a delimited text table.
+func newTextReader(in io.Reader, param string) (recordReader, error) {
+	return newCSVReader(in), nil
+}
+
+// csvReader reads a delimited text table. It keeps the text of the
+// current record, to find the field of a parse error.
+type csvReader struct {
+	*csv.Reader
+	in *lineKeeper
+}
+
+// newCSVReader returns a reader for delimited text, with the field
+// separator defined by the common flags.
+func newCSVReader(in io.Reader) *csvReader {
+	lk := &lineKeeper{r: in, first: 1}
+	r := csv.NewReader(lk)
+	r.Comma = delimRune()
+	r.FieldsPerRecord = -1
+	return &csvReader{r, lk}
+}
+
+func (r *csvReader) Read() ([]string, error) {
+	rec, err := r.Reader.Read()
+	if err == nil {
+		// the next record starts after the last field of this record
+		ln, _ := r.FieldPos(len(rec) - 1)
+		r.in.discard(ln)
+	}
+	return rec, err
+}
+
+// errorField returns the index of the field with a parse error, or -1 if
+// the field is unknown.
+func (r *csvReader) errorField(pe *csv.ParseError) int {
+	start := r.in.lineStart(pe.StartLine)
+	end := r.in.lineStart(pe.Line)
+	if start < 0 || end < 0 {
+		return -1
+	}
+	end += pe.Column - 1
+	if end > len(r.in.buf) {
+		end = len(r.in.buf)
+	}
+	field := 0
+	begin := true   // at the beginning of a field
+	quoted := false // the field starts with a quote
+	inQuotes := false
+	for _, c := range string(r.in.buf[start:end]) {
+		switch {
+		case c == '"' && (begin || quoted):
+			quoted = true
+			inQuotes = !inQuotes
+		case c == r.Comma && !inQuotes:
+			field++
+			quoted = false
+			begin = true
+			continue
+		}
+		begin = false
+	}
+	return field
+}
+
+// lineKeeper is a reader that keeps the text read since a given line.
+type lineKeeper struct {
+	r     io.Reader
+	first int    // number of the first kept line
+	buf   []byte // kept text
+}
+
+func (k *lineKeeper) Read(p []byte) (int, error) {
+	n, err := k.r.Read(p)
+	k.buf = append(k.buf, p[:n]...)
+	return n, err
+}
+
+// discard removes the text before a line.
+func (k *lineKeeper) discard(line int) {
+	i := k.lineStart(line)
+	if i <= 0 {
+		return
+	}
+	k.buf = append(k.buf[:0], k.buf[i:]...)
+	k.first = line
+}
+
+// lineStart returns the position of the start of a line in the kept text,
+// or -1 if the line is not kept.
+func (k *lineKeeper) lineStart(line int) int {
+	if line < k.first {
+		return -1
+	}
+	i := 0
+	for ln := k.first; ln < line; ln++ {
+		j := bytes.IndexByte(k.buf[i:], '\n')
+		if j < 0 {
+			return -1
+		}
+		i += j + 1
+	}
+	return i
+}
+
+// positionReader is a table reader that knows the position of the
+// records in the input.
+type positionReader interface {
+	// FieldPos returns the line and column of a field of the last record
+	// read.
+	FieldPos(field int) (line, column int)
+}
+
+// fieldReader is a table reader that knows the field of a parse error.
+type fieldReader interface {
+	// errorField returns the index of the field with a parse error, or -1
+	// if the field is unknown.
+	errorField(pe *csv.ParseError) int
+}
+
+// memReader reads a table stored in memory.
+type memReader struct {
+	recs [][]string
+}
+
+func (r *memReader) Read() ([]string, error) {
+	if len(r.recs) == 0 {
+		return nil, io.EOF
+	}
+	rec := r.recs[0]
+	r.recs = r.recs[1:]
+	return rec, nil
+}
+
+// cell returns the field of a record, or an empty string if the record is
+// shorter.
+func cell(rec []string, i int) string {
+	if i < len(rec) {
+		return rec[i]
+	}
+	return ""
+}
+
+// textWriter writes a delimited text table.
+type textWriter struct {
+	*csv.Writer
+}
+
+// newTextWriter returns a writer for a delimited text table.
+func newTextWriter(out io.Writer, param string) recordWriter {
+	w := csv.NewWriter(out)
+	w.Comma = delimRune()
+	w.UseCRLF = true
+	return textWriter{w}
+}
+
+func (w textWriter) Flush() error {
+	w.Writer.Flush()
+	return w.Error()
+}
+
+// inTable is an input table.
+type inTable struct {
+	r      recordReader
+	c      io.Closer
+	schema *tableSchema
+	header []string
+	name   string // file name
+	rec    int    // records read
+	peeked [][]string
+
+	// if set, the values are not checked with the types of the schema
+	untyped bool
+}
+
+// openInput opens the input table defined by the common flags.
+func openInput() (*inTable, error) {
+	return openTable(input)
+}
+
+// openTable opens a table from a file, or from stdin if name is empty,
+// using the format, and the schema, defined by the common flags.
+func openTable(name string) (*inTable, error) {
+	switch ragged {
+	case "", "error", "pad", "truncate", "skip":
+	default:
+		return nil, fmt.Errorf("unknown ragged rows policy: %s", ragged)
+	}
+	switch dups {
+	case "", "warn", "error", "rename":
+	default:
+		return nil, fmt.Errorf("unknown duplicated columns policy: %s", dups)
+	}
+	t, err := openTableAs(name, from)
+	if err != nil || len(schema) == 0 {
+		return t, err
+	}
+	if t.schema, err = readSchema(schema); err != nil {
+		t.Close()
+		return nil, err
+	}
+	return t, nil
+}
+
+// openTableAs opens a table from a file, or from stdin if name is empty,
+// with the given format (if empty, the format is detected from the file
+// name). Compressed tables are decompressed, and text tables are converted
+// to UTF-8.
+func openTableAs(name, format string) (*inTable, error) {
+	comp, base := getCompression(name)
+	tf, param, err := getFormat(format, base)
+	if err != nil {
+		return nil, err
+	}
+	if tf.newReader == nil {
+		return nil, fmt.Errorf("table format %s can not be read", tf.name)
+	}
+	t := &inTable{name: name}
+	if len(name) == 0 {
+		t.name = "stdin"
+	}
+	var in io.Reader = os.Stdin
+	var f io.Closer
+	if len(name) > 0 {
+		fl, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		in, f = fl, fl
+	}
+	// without a compression extension, the compression is detected from
+	// the content
+	in, dc, err := decompressInput(in, comp)
+	t.c = closers{dc, f}
+	if err == nil && !tf.binary {
+		in, err = decodeInput(in, encoding)
+	}
+	if err == nil {
+		t.r, err = tf.newReader(in, param)
+	}
+	if err != nil {
+		t.Close()
+		return nil, t.error(err, false)
+	}
+	return t, nil
+}
+
+// readError is an error found reading a table.
+type readError struct {
+	file   string
+	pos    string // position of the record (e.g. "line 3")
+	column string // column name
+	err    error
+}
+
+func (e *readError) Error() string {
+	s := e.file
+	if len(e.pos) > 0 {
+		s += ": " + e.pos
+	}
+	if len(e.column) > 0 {
+		s += fmt.Sprintf(": column %q", e.column)
+	}
+	return s + ": " + e.err.Error()
+}
+
+// error returns an error of the table with the context of the error, i.e.
+// the file name, and if rec is true, the position of the record being
+// read. If err is a readError, only the missing context is added.
+func (t *inTable) error(err error, rec bool) error {
+	re, ok := err.(*readError)
+	if !ok {
+		re = &readError{err: err}
+		if pe, ok := err.(*csv.ParseError); ok {
+			re.pos = fmt.Sprintf("line %d", pe.Line)
+			re.err = pe.Err
+			i := -1
+			if fr, ok := t.r.(fieldReader); ok {
+				i = fr.errorField(pe)
+			}
+			switch {
+			case i >= 0 && i < len(t.header):
+				re.column = t.header[i]
+			case i >= 0:
+				re.pos += fmt.Sprintf(", field %d", i+1)
+			default:
+				re.pos += fmt.Sprintf(", column %d", pe.Column)
+			}
+		}
+	}
+	if len(re.file) == 0 {
+		re.file = t.name
+	}
+	if len(re.pos) == 0 && rec {
+		re.pos = fmt.Sprintf("record %d", t.rec+1)
+	}
+	return re
+}
+
+// Read reads a record from the table. Records with a different number of
+// fields than the header are handled with the ragged rows policy.
+func (t *inTable) Read() ([]string, error) {
+	if len(t.peeked) > 0 {
+		rec := t.peeked[0]
+		t.peeked = t.peeked[1:]
+		return rec, nil
+	}
+	return t.next()
+}
+
+// Peek returns the next n records of the table (or less, if the table
+// ends before), without consuming them. It must be called after the header
+// is read.
+func (t *inTable) Peek(n int) ([][]string, error) {
+	for len(t.peeked) < n {
+		rec, err := t.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		t.peeked = append(t.peeked, rec)
+	}
+	if len(t.peeked) < n {
+		n = len(t.peeked)
+	}
+	return t.peeked[:n], nil
+}
+
+// next reads the next record of the underlying reader. If the table has
+// a schema, the values of the record are checked with the types of the
+// schema.
+func (t *inTable) next() ([]string, error) {
+	isHeader := t.header == nil
+	rec, err := t.record()
+	if err != nil || isHeader || t.schema == nil || t.untyped {
+		return rec, err
+	}
+	for i, v := range rec {
+		if i >= len(t.header) || len(v) == 0 {
+			continue
+		}
+		c, ok := t.schema.cols[t.header[i]]
+		if !ok || c.validType(v) {
+			continue
+		}
+		return nil, &readError{t.name, t.pos(), t.header[i], fmt.Errorf("invalid %s value %q", c.typ, v)}
+	}
+	return rec, nil
+}
+
+// record reads the next record of the underlying reader, using the
+// ragged rows policy.
+func (t *inTable) record() ([]string, error) {
+	for {
+		rec, err := t.r.Read()
+		if err != nil {
+			if err == io.EOF {
+				return nil, err
+			}
+			return nil, t.error(err, true)
+		}
+		t.rec++
+		if t.header == nil {
+			return t.readHeader(rec)
+		}
+		if len(rec) == len(t.header) {
+			return rec, nil
+		}
+		switch ragged {
+		case "pad", "truncate":
+			if len(rec) < len(t.header) {
+				return append(rec, make([]string, len(t.header)-len(rec))...), nil
+			}
+			if ragged == "truncate" {
+				return rec[:len(t.header)], nil
+			}
+		case "skip":
+			fmt.Fprintf(os.Stderr, "%s: warning: %s: %s: skipping record with %d fields, expecting %d\n", cmdapp.Name, t.name, t.pos(), len(rec), len(t.header))
+			continue
+		}
+		col := ""
+		if len(rec) < len(t.header) {
+			// the first missing column
+			col = t.header[len(rec)]
+		}
+		return nil, &readError{t.name, t.pos(), col, fmt.Errorf("expecting %d fields, found %d", len(t.header), len(rec))}
+	}
+}
+
+// skipHeader reads the header of a table whose header was already read,
+// e.g. when the file is opened again, and sets it without checking it
+// again.
+func (t *inTable) skipHeader(header []string) error {
+	if _, err := t.r.Read(); err != nil {
+		return t.error(err, true)
+	}
+	t.rec++
+	t.header = header
+	return nil
+}
+
+// readHeader sets the header of the table, handling the duplicated column
+// names with the duplicated columns policy.
+func (t *inTable) readHeader(rec []string) ([]string, error) {
+	t.header = rec
+	dp := duplicatedColumns(rec)
+	if len(dp) == 0 {
+		return rec, nil
+	}
+	switch dups {
+	case "error":
+		return nil, &readError{t.name, t.pos(), "", fmt.Errorf("duplicated column names: %s", strings.Join(dp, ", "))}
+	case "rename":
+		t.header = dedupHeader(rec)
+	default:
+		fmt.Fprintf(os.Stderr, "%s: warning: %s: duplicated column names: %s\n", cmdapp.Name, t.name, strings.Join(dp, ", "))
+	}
+	return t.header, nil
+}
+
+// duplicatedColumns returns the names found more than once in a header.
+func duplicatedColumns(header []string) []string {
+	var dp []string
+	seen := make(map[string]int)
+	for _, h := range header {
+		seen[h]++
+		if seen[h] == 2 {
+			dp = append(dp, h)
+		}
+	}
+	return dp
+}
+
+// dedupHeader returns a header in which the duplicated names are renamed
+// by adding the number of the repetition (e.g. Value, Value_2). Numbers
+// that produce a name already in the header are skipped.
+func dedupHeader(header []string) []string {
+	cols := make([]string, len(header))
+	used := make(map[string]bool)
+	for _, h := range header {
+		used[h] = true
+	}
+	count := make(map[string]int)
+	for i, h := range header {
+		count[h]++
+		if count[h] == 1 {
+			cols[i] = h
+			continue
+		}
+		for {
+			nm := h + "_" + strconv.Itoa(count[h])
+			if !used[nm] {
+				cols[i] = nm
+				used[nm] = true
+				break
+			}
+			count[h]++
+		}
+	}
+	return cols
+}
+
+// pos returns the position of the last record read.
+func (t *inTable) pos() string {
+	if pr, ok := t.r.(positionReader); ok {
+		ln, _ := pr.FieldPos(0)
+		return fmt.Sprintf("line %d", ln)
+	}
+	return fmt.Sprintf("record %d", t.rec)
+}
+
+// Types returns the declared types of the columns, from the schema, or
+// from the format, or nil if the types are unknown.
+func (t *inTable) Types() []colType {
+	if t.schema != nil && t.header != nil {
+		return t.schema.types(t.header, columnTypes(t.r))
+	}
+	return columnTypes(t.r)
+}
+
+// Close closes the table.
+func (t *inTable) Close() error {
+	if t.c == nil {
+		return nil
+	}
+	err := t.c.Close()
+	t.c = nil
+	return err
+}
+
+// outTable is an output table.
+type outTable struct {
+	w      recordWriter
+	c      io.Closer
+	noHead bool // skip the header
+	rec    int  // records written
+	closed bool
+}
+
+// openOutput creates the output table defined by the common flags.
+func openOutput() (*outTable, error) {
+	return createTable(output)
+}
+
+// createTable creates a table on a file, or on stdout if name is empty.
+// If the file has the extension of a compression format, the table is
+// compressed.
+func createTable(name string) (*outTable, error) {
+	comp, base := getCompression(name)
+	tf, param, err := getFormat(to, base)
+	if err != nil {
+		return nil, err
+	}
+	if tf.newWriter == nil {
+		return nil, fmt.Errorf("table format %s can not be written", tf.name)
+	}
+	t := &outTable{noHead: noHead && !tf.keyed}
+	var out io.Writer = os.Stdout
+	if len(name) > 0 {
+		f, err := os.Create(name)
+		if err != nil {
+			return nil, err
+		}
+		out = f
+		t.c = f
+		if comp != nil {
+			cw, err := comp.newWriter(f)
+			if err != nil {
+				f.Close()
+				return nil, fmt.Errorf("%s: %v", comp.name, err)
+			}
+			out = cw
+			t.c = closers{cw, f}
+		}
+	}
+	t.w = tf.newWriter(out, param)
+	return t, nil
+}
+
+// Write writes a record into the table. The first record written must be
+// the header.
+func (t *outTable) Write(record []string) error {
+	t.rec++
+	if t.rec == 1 && t.noHead {
+		return nil
+	}
+	return t.w.Write(record)
+}
+
+// Close flushes the table and closes its file. Close can be called
+// several times.
+func (t *outTable) Close() error {
+	if t.closed {
+		return nil
+	}
+	t.closed = true
+	err := t.w.Flush()
+	if t.c != nil {
+		if e := t.c.Close(); err == nil {
+			err = e
+		}
+	}
+	return err
+}
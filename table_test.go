@@ -0,0 +1,122 @@
+// Copyright (c) 2016, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+// memWriter stores the records written.
+type memWriter struct {
+	recs [][]string
+}
+
+func (w *memWriter) Write(record []string) error {
+	w.recs = append(w.recs, record)
+	return nil
+}
+
+func TestRagged(t *testing.T) {
+	recs := [][]string{
+		[]string{"a", "b", "c"},
+		[]string{"1", "2", "3"},
+		[]string{"4", "5"},
+		[]string{"6", "7", "8", "9"},
+	}
+	tests := []struct {
+		policy string
+		rows   []string
+		fails  bool
+	}{
+		{"", []string{"a b c", "1 2 3"}, true},
+		{"pad", []string{"a b c", "1 2 3", "4 5 "}, true},
+		{"truncate", []string{"a b c", "1 2 3", "4 5 ", "6 7 8"}, false},
+		{"skip", []string{"a b c", "1 2 3"}, false},
+	}
+	defer func() { ragged = "" }()
+	for _, ts := range tests {
+		ragged = ts.policy
+		r := &inTable{r: &memReader{recs: append([][]string{}, recs...)}, name: "data"}
+		var rows []string
+		var err error
+		for {
+			var rec []string
+			rec, err = r.Read()
+			if err != nil {
+				break
+			}
+			rows = append(rows, strings.Join(rec, " "))
+		}
+		if (err != io.EOF) != ts.fails {
+			t.Errorf("Ragged: %q: unexpected error value: %v", ts.policy, err)
+		}
+		if strings.Join(rows, "|") != strings.Join(ts.rows, "|") {
+			t.Errorf("Ragged: %q: expecting %q, found %q", ts.policy, ts.rows, rows)
+		}
+	}
+}
+
+func TestReadError(t *testing.T) {
+	tests := []struct {
+		blob string
+		x    string
+	}{
+		{"a\tb\n1\t\"x\n", "data.tsv: line 2: column \"b\": extraneous or missing \" in quoted-field"},
+		{"a\tb\tc\n1\t2\t3\n\"x\ty\"\"\tz\"\t4\tw\"v\n", "data.tsv: line 3: column \"c\": bare \" in non-quoted-field"},
+		{"a\tb\tc\n\"x\n\ty\"\tz\tw\"\n", "data.tsv: line 3: column \"c\": bare \" in non-quoted-field"},
+		{"a\tb\n1\t2\n3\n", "data.tsv: line 3: column \"b\": expecting 2 fields, found 1"},
+		{"a\tb\"c\n", "data.tsv: line 1, field 2: bare \" in non-quoted-field"},
+	}
+	for _, ts := range tests {
+		r, err := newTextReader(strings.NewReader(ts.blob), "")
+		if err != nil {
+			t.Fatalf("Read error: unexpected error: %v", err)
+		}
+		in := &inTable{r: r, name: "data.tsv"}
+		for err == nil {
+			_, err = in.Read()
+		}
+		if err.Error() != ts.x {
+			t.Errorf("Read error: expecting %q, found %q", ts.x, err.Error())
+		}
+	}
+
+	in := &inTable{r: &memReader{}, name: "data.json", rec: 2}
+	err := in.error(&readError{column: "Cost", err: io.ErrUnexpectedEOF}, true)
+	x := "data.json: record 3: column \"Cost\": unexpected EOF"
+	if err.Error() != x {
+		t.Errorf("Read error: expecting %q, found %q", x, err.Error())
+	}
+}
+
+func TestDuplicatedColumns(t *testing.T) {
+	header := []string{"Value", "Item", "Value", "Value_2", "Value"}
+	tests := []struct {
+		policy string
+		header string
+		fails  bool
+	}{
+		{"warn", "Value Item Value Value_2 Value", false},
+		{"error", "", true},
+		{"rename", "Value Item Value_3 Value_2 Value_4", false},
+	}
+	defer func() { dups = "" }()
+	for _, ts := range tests {
+		dups = ts.policy
+		r := &inTable{r: &memReader{recs: [][]string{header}}, name: "data"}
+		rec, err := r.Read()
+		if (err != nil) != ts.fails {
+			t.Errorf("Dups: %q: unexpected error value: %v", ts.policy, err)
+		}
+		if s := strings.Join(rec, " "); s != ts.header {
+			t.Errorf("Dups: %q: expecting %q, found %q", ts.policy, ts.header, s)
+		}
+	}
+	if dp := duplicatedColumns(header); strings.Join(dp, " ") != "Value" {
+		t.Errorf("Dups: expecting duplicated %q, found %q", "Value", dp)
+	}
+}
@@ -0,0 +1,274 @@
+// Copyright (c) 2016, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Thrift compact protocol types.
+const (
+	thriftStop   = 0
+	thriftTrue   = 1
+	thriftFalse  = 2
+	thriftByte   = 3
+	thriftI16    = 4
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftDouble = 7
+	thriftBinary = 8
+	thriftList   = 9
+	thriftSet    = 10
+	thriftMap    = 11
+	thriftStruct = 12
+)
+
+// thriftWriter encodes values with the thrift compact protocol.
+type thriftWriter struct {
+	b    []byte
+	last []int16 // last field id of each open struct
+}
+
+func (w *thriftWriter) varint(v uint64) {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], v)
+	w.b = append(w.b, buf[:n]...)
+}
+
+func (w *thriftWriter) zigzag(v int64) {
+	w.varint(uint64((v << 1) ^ (v >> 63)))
+}
+
+// field writes a field header.
+func (w *thriftWriter) field(id int16, tp byte) {
+	last := &w.last[len(w.last)-1]
+	if d := id - *last; d > 0 && d < 16 {
+		w.b = append(w.b, byte(d)<<4|tp)
+	} else {
+		w.b = append(w.b, tp)
+		w.zigzag(int64(id))
+	}
+	*last = id
+}
+
+// begin starts a struct.
+func (w *thriftWriter) begin() {
+	w.last = append(w.last, 0)
+}
+
+// end ends a struct.
+func (w *thriftWriter) end() {
+	w.b = append(w.b, thriftStop)
+	w.last = w.last[:len(w.last)-1]
+}
+
+func (w *thriftWriter) i32(id int16, v int32) {
+	w.field(id, thriftI32)
+	w.zigzag(int64(v))
+}
+
+func (w *thriftWriter) i64(id int16, v int64) {
+	w.field(id, thriftI64)
+	w.zigzag(v)
+}
+
+func (w *thriftWriter) binary(id int16, v []byte) {
+	w.field(id, thriftBinary)
+	w.bytes(v)
+}
+
+func (w *thriftWriter) bytes(v []byte) {
+	w.varint(uint64(len(v)))
+	w.b = append(w.b, v...)
+}
+
+// structField starts a struct field, it must be closed with end.
+func (w *thriftWriter) structField(id int16) {
+	w.field(id, thriftStruct)
+	w.begin()
+}
+
+// list writes a list header.
+func (w *thriftWriter) list(id int16, tp byte, n int) {
+	w.field(id, thriftList)
+	if n < 15 {
+		w.b = append(w.b, byte(n)<<4|tp)
+		return
+	}
+	w.b = append(w.b, 0xF0|tp)
+	w.varint(uint64(n))
+}
+
+// thriftStructVal is a decoded thrift struct. Integers are decoded as
+// int64, binaries as []byte, lists and sets as []interface{}, and structs
+// as thriftStructVal. Maps are ignored.
+type thriftStructVal map[int16]interface{}
+
+func (s thriftStructVal) int(id int16) int64 {
+	v, _ := s[id].(int64)
+	return v
+}
+
+func (s thriftStructVal) bytes(id int16) []byte {
+	v, _ := s[id].([]byte)
+	return v
+}
+
+func (s thriftStructVal) strct(id int16) thriftStructVal {
+	v, _ := s[id].(thriftStructVal)
+	return v
+}
+
+func (s thriftStructVal) list(id int16) []interface{} {
+	v, _ := s[id].([]interface{})
+	return v
+}
+
+// thriftReader decodes values with the thrift compact protocol.
+type thriftReader struct {
+	r     io.ByteReader
+	depth int
+}
+
+var errThriftDepth = errors.New("thrift: structure too deep")
+
+// readStruct reads a struct.
+func (r *thriftReader) readStruct() (thriftStructVal, error) {
+	r.depth++
+	defer func() { r.depth-- }()
+	if r.depth > 64 {
+		return nil, errThriftDepth
+	}
+	s := make(thriftStructVal)
+	var id int16
+	for {
+		h, err := r.r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		tp := h & 0x0F
+		if tp == thriftStop {
+			return s, nil
+		}
+		if d := h >> 4; d != 0 {
+			id += int16(d)
+		} else {
+			v, err := binary.ReadVarint(r.r)
+			if err != nil {
+				return nil, err
+			}
+			id = int16(v)
+		}
+		switch tp {
+		case thriftTrue:
+			s[id] = true
+		case thriftFalse:
+			s[id] = false
+		default:
+			v, err := r.readValue(tp)
+			if err != nil {
+				return nil, err
+			}
+			s[id] = v
+		}
+	}
+}
+
+// readValue reads a value of a given type.
+func (r *thriftReader) readValue(tp byte) (interface{}, error) {
+	switch tp {
+	case thriftTrue, thriftFalse:
+		// booleans in lists
+		b, err := r.r.ReadByte()
+		return b == thriftTrue, err
+	case thriftByte:
+		b, err := r.r.ReadByte()
+		return int64(int8(b)), err
+	case thriftI16, thriftI32, thriftI64:
+		return binary.ReadVarint(r.r)
+	case thriftDouble:
+		var b [8]byte
+		for i := range b {
+			c, err := r.r.ReadByte()
+			if err != nil {
+				return nil, err
+			}
+			b[i] = c
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(b[:])), nil
+	case thriftBinary:
+		n, err := binary.ReadUvarint(r.r)
+		if err != nil {
+			return nil, err
+		}
+		if n > 1<<30 {
+			return nil, errors.New("thrift: binary too large")
+		}
+		b := make([]byte, n)
+		for i := range b {
+			if b[i], err = r.r.ReadByte(); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case thriftList, thriftSet:
+		h, err := r.r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		n := uint64(h >> 4)
+		if n == 15 {
+			if n, err = binary.ReadUvarint(r.r); err != nil {
+				return nil, err
+			}
+		}
+		var l []interface{}
+		for i := uint64(0); i < n; i++ {
+			v, err := r.readValue(h & 0x0F)
+			if err != nil {
+				return nil, err
+			}
+			l = append(l, v)
+		}
+		return l, nil
+	case thriftMap:
+		n, err := binary.ReadUvarint(r.r)
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			return nil, nil
+		}
+		kv, err := r.r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		for i := uint64(0); i < n; i++ {
+			if _, err := r.readValue(kv >> 4); err != nil {
+				return nil, err
+			}
+			if _, err := r.readValue(kv & 0x0F); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	case thriftStruct:
+		return r.readStruct()
+	}
+	return nil, fmt.Errorf("thrift: unknown type %d", tp)
+}
+
+// newThriftReader returns a thrift decoder.
+func newThriftReader(r io.Reader) *thriftReader {
+	if br, ok := r.(io.ByteReader); ok {
+		return &thriftReader{r: br}
+	}
+	return &thriftReader{r: bufio.NewReader(r)}
+}
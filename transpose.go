@@ -0,0 +1,223 @@
+// Copyright (c) 2016, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"encoding/gob"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/js-arias/cmdapp"
+)
+
+var transposeCmd = &cmdapp.Command{
+	Run: transposeRun,
+	UsageLine: `transpose [-f <char>] [--dups <policy>] [--encoding <name>]
+	[--from <format>] [-i|--input <file>] [-n|--no-header]
+	[-o|--output <file>] [--ragged <policy>] [--schema <file>]
+	[--to <format>] [--disk]`,
+	Short: "swaps the rows and columns of a table",
+	Long: `
+Command transpose outputs a table in which the rows of the input table are
+the columns, and the columns are the rows. The header of the input table
+becomes the first column of the new table, and the first column of the
+input table becomes the new header. For example, the output of the stats
+command can be transposed to have a row for each column.
+
+By default the whole table is kept in memory. With --disk, the table is
+stored in a temporary file, and the output is built by reading that file
+several times, keeping in memory only about a million fields at a time,
+so it can be used with tables with many columns.
+
+Options are:
+
+    -f <char>
+      Sets the field separation character. By default the value is the tab
+      character.
+
+    --dups <policy>
+      Sets how to handle duplicated column names in the header, one of
+      warn (the default), error, or rename. See 'tables help formats'.
+
+    --encoding <name>
+      Sets the character encoding of the input. By default the encoding
+      is detected from the input. See 'tables help formats' for the
+      available encodings.
+
+    --from <format>
+      Sets the format of the input table. By default the format is
+      detected from the extension of the input file, or it is a delimited
+      text table. See 'tables help formats' for the available formats.
+
+    -i <file>
+    --input <file>
+      Read the table from <file> instead of stdin.
+
+    -n
+    --no-header
+      If set, the table will be printed without a header.
+
+    -o <file>
+    --output <file>
+      Write the resulting table to <file> instead of stdout.
+
+    --ragged <policy>
+      Sets how to handle records with a different number of fields than
+      the header, one of error (the default), pad, truncate, or skip. See
+      'tables help formats'.
+
+    --schema <file>
+      Read the types of the columns of the input from a schema file. See
+      'tables help schema'.
+
+    --to <format>
+      Sets the format of the output table. By default the format is
+      detected from the extension of the output file, or it is a delimited
+      text table. See 'tables help formats' for the available formats.
+
+    --disk
+      If set, the table is stored in a temporary file, instead of memory.
+	`,
+}
+
+var transposeDisk bool // use a temporary file, --disk
+
+// transposeCells is the maximum number of fields kept in memory when
+// transposing with a temporary file.
+const transposeCells = 1 << 20
+
+func init() {
+	initCommonFlags(transposeCmd)
+	transposeCmd.Flag.BoolVar(&transposeDisk, "disk", false, "")
+}
+
+func transposeRun(c *cmdapp.Command, args []string) error {
+	r, err := openInput()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	w, err := openOutput()
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	if transposeDisk {
+		err = transposeFile(r, w, transposeCells)
+	} else {
+		err = transposeMem(r, w)
+	}
+	if err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// transposeMem writes the columns of a table as rows, keeping the table
+// in memory.
+func transposeMem(r recordReader, w rowWriter) error {
+	var recs [][]string
+	width := 0
+	for {
+		rec, err := r.Read()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return err
+		}
+		recs = append(recs, rec)
+		if len(rec) > width {
+			width = len(rec)
+		}
+	}
+	return writeTransposed(recs, width, w)
+}
+
+// transposeFile writes the columns of a table as rows, storing the table
+// in a temporary file. The file is read as many times as required to keep
+// in memory at most the given number of fields (but at least a column).
+func transposeFile(r recordReader, w rowWriter, cells int) error {
+	f, err := ioutil.TempFile("", "tables-transpose")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	bw := bufio.NewWriter(f)
+	enc := gob.NewEncoder(bw)
+	n, width := 0, 0
+	for {
+		rec, err := r.Read()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return err
+		}
+		if err := enc.Encode(rec); err != nil {
+			return err
+		}
+		n++
+		if len(rec) > width {
+			width = len(rec)
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	if n == 0 {
+		return nil
+	}
+
+	block := cells / n
+	if block < 1 {
+		block = 1
+	}
+	for from := 0; from < width; from += block {
+		to := from + block
+		if to > width {
+			to = width
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		dec := gob.NewDecoder(bufio.NewReader(f))
+		recs := make([][]string, 0, n)
+		for i := 0; i < n; i++ {
+			var rec []string
+			if err := dec.Decode(&rec); err != nil {
+				return err
+			}
+			cols := make([]string, to-from)
+			for j := range cols {
+				cols[j] = cell(rec, from+j)
+			}
+			recs = append(recs, cols)
+		}
+		if err := writeTransposed(recs, to-from, w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeTransposed writes the first width columns of a set of records as
+// rows.
+func writeTransposed(recs [][]string, width int, w rowWriter) error {
+	for j := 0; j < width; j++ {
+		row := make([]string, len(recs))
+		for i, rec := range recs {
+			row[i] = cell(rec, j)
+		}
+		if err := w.Write(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+// Copyright (c) 2016, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTranspose(t *testing.T) {
+	recs := [][]string{
+		[]string{"Stat", "a", "b", "c"},
+		[]string{"Sum", "1", "2", "3"},
+		[]string{"Mean", "4", "", "6"},
+	}
+	x := []string{
+		"Stat Sum Mean",
+		"a 1 4",
+		"b 2 ",
+		"c 3 6",
+	}
+	for _, cells := range []int{-1, 1, 5, 100} {
+		w := &memWriter{}
+		r := &memReader{recs: append([][]string{}, recs...)}
+		var err error
+		if cells < 0 {
+			err = transposeMem(r, w)
+		} else {
+			err = transposeFile(r, w, cells)
+		}
+		if err != nil {
+			t.Errorf("Transpose: cells %d: unexpected error: %v", cells, err)
+			continue
+		}
+		var rows []string
+		for _, rec := range w.recs {
+			rows = append(rows, strings.Join(rec, " "))
+		}
+		if strings.Join(rows, "|") != strings.Join(x, "|") {
+			t.Errorf("Transpose: cells %d: expecting %q, found %q", cells, x, rows)
+		}
+	}
+}
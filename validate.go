@@ -0,0 +1,299 @@
+// Copyright (c) 2016, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/js-arias/cmdapp"
+)
+
+var validateCmd = &cmdapp.Command{
+	Run: validateRun,
+	UsageLine: `validate [-f <char>] [--dups <policy>] [--encoding <name>]
+	[--from <format>] [-i|--input <file>] [-n|--no-header]
+	[-o|--output <file>] [--ragged <policy>] --schema <file>
+	[--to <format>]`,
+	Short: "validates a table with a schema",
+	Long: `
+Command validate checks the values of a table with the rules of a schema
+file, and outputs a table with the violations found, with the columns Row
+(the number of the row, starting at 1 with the first row after the
+header, or 0 for required columns not found in the table), Column, Value,
+and Rule. If any rule fails, the command ends with an error.
+
+The schema file is a table with a row for each column, and the columns
+(names are case insensitive):
+
+    Column
+      The name of the column.
+
+    Type
+      The type of the values, one of integer, float, boolean, date, or
+      string. See 'tables help schema' for the accepted values of each
+      type.
+
+    Required
+      If true (true, yes, y, or 1), the column must be in the table, and
+      its values can not be empty.
+
+    Key
+      If true, the column is part of the key of the table. The
+      combination of the values of all the key columns must be unique.
+
+    Values
+      A list of the allowed values, separated by semicolons.
+
+    Pattern
+      A regular expression that the values must match. The expression
+      must match the whole value.
+
+    Min
+    Max
+      The minimum and maximum allowed values, compared as dates in date
+      columns, and as numbers in numeric columns (or if both values are
+      numbers).
+
+Only the column Column is required, and all the other columns are
+optional, so the output of the schema command can be used as a schema file
+(including the Min and Max values found in the table). Empty values are
+only checked by the Required and Key rules. Columns of the table not
+defined in the schema are not checked.
+
+Options are:
+
+    -f <char>
+      Sets the field separation character. By default the value is the tab
+      character.
+
+    --dups <policy>
+      Sets how to handle duplicated column names in the header, one of
+      warn (the default), error, or rename. See 'tables help formats'.
+
+    --encoding <name>
+      Sets the character encoding of the input. By default the encoding
+      is detected from the input. See 'tables help formats' for the
+      available encodings.
+
+    --from <format>
+      Sets the format of the input table. By default the format is
+      detected from the extension of the input file, or it is a delimited
+      text table. See 'tables help formats' for the available formats.
+
+    -i <file>
+    --input <file>
+      Read the table from <file> instead of stdin.
+
+    -n
+    --no-header
+      If set, the table will be printed without a header.
+
+    -o <file>
+    --output <file>
+      Write the resulting table to <file> instead of stdout.
+
+    --ragged <policy>
+      Sets how to handle records with a different number of fields than
+      the header, one of error (the default), pad, truncate, or skip. See
+      'tables help formats'.
+
+    --schema <file>
+      The schema file with the rules. This option is required.
+
+    --to <format>
+      Sets the format of the output table. By default the format is
+      detected from the extension of the output file, or it is a delimited
+      text table. See 'tables help formats' for the available formats.
+	`,
+}
+
+func init() {
+	initCommonFlags(validateCmd)
+}
+
+func validateRun(c *cmdapp.Command, args []string) error {
+	if len(schema) == 0 {
+		return errors.New("a schema file must be defined with --schema")
+	}
+	r, err := openInput()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	// type errors are reported as violations
+	r.untyped = true
+	w, err := openOutput()
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	header, err := r.Read()
+	if err != nil {
+		return err
+	}
+	v := newValidator(r.schema, header)
+
+	if err := w.Write([]string{"Row", "Column", "Value", "Rule"}); err != nil {
+		return err
+	}
+	n := 0
+	write := func(errs []violation) error {
+		for _, e := range errs {
+			n++
+			if err := w.Write(e.record()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := write(v.checkHeader()); err != nil {
+		return err
+	}
+	for row := 1; ; row++ {
+		rec, err := r.Read()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return err
+		}
+		if err := write(v.check(row, rec)); err != nil {
+			return err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	if n > 0 {
+		return fmt.Errorf("%d violations found", n)
+	}
+	return nil
+}
+
+// violation is a failed rule.
+type violation struct {
+	row    int // 0 for the header
+	column string
+	value  string
+	rule   string
+}
+
+func (v violation) record() []string {
+	return []string{strconv.Itoa(v.row), v.column, v.value, v.rule}
+}
+
+// validator checks the rows of a table.
+type validator struct {
+	s      *tableSchema
+	header []string
+	cols   []*schemaColumn // schema of each column of the table
+	keys   []int           // key columns
+	seen   map[string]int  // rows of the keys already found
+}
+
+// newValidator returns a validator for a table with a given header.
+func newValidator(s *tableSchema, header []string) *validator {
+	v := &validator{
+		s:      s,
+		header: header,
+		cols:   make([]*schemaColumn, len(header)),
+		seen:   make(map[string]int),
+	}
+	for i, h := range header {
+		if c, ok := s.cols[h]; ok {
+			v.cols[i] = c
+			if c.key {
+				v.keys = append(v.keys, i)
+			}
+		}
+	}
+	return v
+}
+
+// checkHeader returns the required columns not found in the table.
+func (v *validator) checkHeader() []violation {
+	var errs []violation
+	for _, c := range v.s.order {
+		if c.required && !hasColumn(v.header, c.name) {
+			errs = append(errs, violation{column: c.name, rule: "required"})
+		}
+	}
+	return errs
+}
+
+// check returns the violations of a row.
+func (v *validator) check(row int, rec []string) []violation {
+	var errs []violation
+	for i, c := range v.cols {
+		if c == nil {
+			continue
+		}
+		val := cell(rec, i)
+		if len(val) == 0 {
+			if c.required {
+				errs = append(errs, violation{row, c.name, val, "required"})
+			}
+			continue
+		}
+		errs = append(errs, c.check(row, val)...)
+	}
+
+	if len(v.keys) > 0 {
+		vals := make([]string, len(v.keys))
+		names := make([]string, len(v.keys))
+		empty := false
+		for i, k := range v.keys {
+			vals[i] = cell(rec, k)
+			names[i] = v.header[k]
+			if len(vals[i]) == 0 {
+				empty = true
+			}
+		}
+		key := strings.Join(vals, "\x00")
+		switch prev, ok := v.seen[key]; {
+		case empty:
+			errs = append(errs, violation{row, strings.Join(names, ","), strings.Join(vals, ","), "key: empty value"})
+		case ok:
+			errs = append(errs, violation{row, strings.Join(names, ","), strings.Join(vals, ","), fmt.Sprintf("key: duplicate of row %d", prev)})
+		default:
+			v.seen[key] = row
+		}
+	}
+	return errs
+}
+
+// check returns the violations of a non empty value of a column.
+func (c *schemaColumn) check(row int, val string) []violation {
+	var errs []violation
+	fail := func(rule string) {
+		errs = append(errs, violation{row, c.name, val, rule})
+	}
+	if !c.validType(val) {
+		fail("type: " + c.typ)
+		// other rules can not be checked
+		return errs
+	}
+	if len(c.values) > 0 && !inList(c.values, val) {
+		fail("values: " + strings.Join(c.values, "; "))
+	}
+	if c.re != nil && !c.re.MatchString(val) {
+		fail("pattern: " + c.pattern)
+	}
+	if len(c.min) > 0 {
+		if cmp := c.compare(val, c.min); cmp < 0 || cmp == 2 {
+			fail("min: " + c.min)
+		}
+	}
+	if len(c.max) > 0 {
+		if cmp := c.compare(val, c.max); cmp > 0 || cmp == 2 {
+			fail("max: " + c.max)
+		}
+	}
+	return errs
+}
@@ -0,0 +1,78 @@
+// Copyright (c) 2016, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var validateSchema = `Column	Type	Required	Key	Values	Pattern	Min	Max
+Item	integer	yes	yes			1	100
+Flag	boolean			yes; no			
+Code	string	yes			[A-Z]{2}		
+Date	date					2020-01-01	
+Zone		yes					
+`
+
+func TestValidate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tables")
+	if err != nil {
+		t.Fatalf("Validate: unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "schema.tsv")
+	ioutil.WriteFile(name, []byte(validateSchema), 0644)
+	s, err := readSchema(name)
+	if err != nil {
+		t.Fatalf("Validate: unexpected error: %v", err)
+	}
+
+	v := newValidator(s, []string{"Item", "Flag", "Code", "Date", "Other"})
+	errs := v.checkHeader()
+	if len(errs) != 1 || errs[0].column != "Zone" || errs[0].rule != "required" {
+		t.Errorf("Validate: expecting missing column Zone, found %v", errs)
+	}
+	rows := []struct {
+		rec   []string
+		rules []string
+	}{
+		{[]string{"1", "yes", "AB", "2020-02-01", "x"}, nil},
+		{[]string{"2", "", "CD", "", ""}, nil},
+		{[]string{"1", "maybe", "ab", "2019-12-31", ""}, []string{"type: boolean", "pattern: [A-Z]{2}", "min: 2020-01-01", "key: duplicate of row 1"}},
+		{[]string{"x", "no", "", "2020-01-01", ""}, []string{"type: integer", "required"}},
+		{[]string{"200", "No"}, []string{"max: 100", "values: yes; no", "required"}},
+	}
+	for i, r := range rows {
+		errs := v.check(i+1, r.rec)
+		if len(errs) != len(r.rules) {
+			t.Errorf("Validate: row %d: expecting %d violations, found %v", i+1, len(r.rules), errs)
+			continue
+		}
+		for j, e := range errs {
+			if e.rule != r.rules[j] {
+				t.Errorf("Validate: row %d: expecting rule %q, found %q", i+1, r.rules[j], e.rule)
+			}
+		}
+	}
+}
+
+func TestValidateIncomparable(t *testing.T) {
+	tests := []struct {
+		col  *schemaColumn
+		rule string
+	}{
+		{&schemaColumn{name: "Date", typ: dateSchema, min: "soon"}, "min: soon"},
+		{&schemaColumn{name: "Date", typ: dateSchema, max: "soon"}, "max: soon"},
+	}
+	for _, ts := range tests {
+		errs := ts.col.check(1, "2020-01-01")
+		if len(errs) != 1 || errs[0].rule != ts.rule {
+			t.Errorf("Validate: expecting rule %q, found %v", ts.rule, errs)
+		}
+	}
+}
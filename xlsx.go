@@ -0,0 +1,325 @@
+// Copyright (c) 2016, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// readZip reads a zip file from a reader.
+func readZip(in io.Reader) (*zip.Reader, error) {
+	b, err := ioutil.ReadAll(in)
+	if err != nil {
+		return nil, err
+	}
+	return zip.NewReader(bytes.NewReader(b), int64(len(b)))
+}
+
+// unmarshalZipFile decodes an XML file stored in a zip file.
+func unmarshalZipFile(z *zip.Reader, name string, v interface{}) error {
+	for _, f := range z.File {
+		if f.Name != name {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		return xml.NewDecoder(rc).Decode(v)
+	}
+	return fmt.Errorf("file %s not found", name)
+}
+
+// padRecords sets all the records to the length of the longest record.
+func padRecords(recs [][]string) {
+	n := 0
+	for _, rec := range recs {
+		if len(rec) > n {
+			n = len(rec)
+		}
+	}
+	for i, rec := range recs {
+		for len(rec) < n {
+			rec = append(rec, "")
+		}
+		recs[i] = rec
+	}
+}
+
+// xlsxRelNS is the name space of XLSX relationships.
+const xlsxRelNS = "http://schemas.openxmlformats.org/officeDocument/2006/relationships"
+
+// xlsxText is a text of an XLSX file, that can be made of several runs.
+type xlsxText struct {
+	T string `xml:"t"`
+	R []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxText) String() string {
+	s := t.T
+	for _, r := range t.R {
+		s += r.T
+	}
+	return s
+}
+
+// xlsxSheet is the content of an XLSX worksheet.
+type xlsxSheet struct {
+	Rows []struct {
+		Cells []struct {
+			R  string   `xml:"r,attr"`
+			T  string   `xml:"t,attr"`
+			V  string   `xml:"v"`
+			IS xlsxText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// newXLSXReader returns a reader for XLSX workbooks. The parameter is the
+// name or the index (starting at 1) of the sheet, by default the first sheet
+// is read.
+func newXLSXReader(in io.Reader, param string) (recordReader, error) {
+	z, err := readZip(in)
+	if err != nil {
+		return nil, err
+	}
+	var wb struct {
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+			ID   string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := unmarshalZipFile(z, "xl/workbook.xml", &wb); err != nil {
+		return nil, err
+	}
+	if len(wb.Sheets) == 0 {
+		return nil, fmt.Errorf("xlsx: workbook without sheets")
+	}
+	sheet := -1
+	if len(param) == 0 {
+		sheet = 0
+	}
+	for i, s := range wb.Sheets {
+		if s.Name == param {
+			sheet = i
+			break
+		}
+	}
+	if sheet == -1 {
+		if i, err := strconv.Atoi(param); err == nil && i > 0 && i <= len(wb.Sheets) {
+			sheet = i - 1
+		} else {
+			return nil, fmt.Errorf("xlsx: sheet %s not found", param)
+		}
+	}
+
+	var rels struct {
+		Rels []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := unmarshalZipFile(z, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, err
+	}
+	target := ""
+	for _, r := range rels.Rels {
+		if r.ID == wb.Sheets[sheet].ID {
+			target = r.Target
+			break
+		}
+	}
+	if strings.HasPrefix(target, "/") {
+		target = target[1:]
+	} else {
+		target = path.Join("xl", target)
+	}
+
+	var sst struct {
+		SI []xlsxText `xml:"si"`
+	}
+	// shared strings are optional
+	unmarshalZipFile(z, "xl/sharedStrings.xml", &sst)
+
+	var ws xlsxSheet
+	if err := unmarshalZipFile(z, target, &ws); err != nil {
+		return nil, err
+	}
+	r := &memReader{}
+	for _, row := range ws.Rows {
+		var rec []string
+		for _, c := range row.Cells {
+			col := len(rec)
+			if len(c.R) > 0 {
+				col = xlsxColumn(c.R)
+			}
+			for len(rec) <= col {
+				rec = append(rec, "")
+			}
+			switch c.T {
+			case "s":
+				i, err := strconv.Atoi(c.V)
+				if err != nil || i < 0 || i >= len(sst.SI) {
+					re := &readError{pos: fmt.Sprintf("cell %s", c.R), err: fmt.Errorf("xlsx: invalid shared string %q", c.V)}
+					if len(r.recs) > 0 && col < len(r.recs[0]) {
+						re.column = r.recs[0][col]
+					}
+					return nil, re
+				}
+				rec[col] = sst.SI[i].String()
+			case "inlineStr":
+				rec[col] = c.IS.String()
+			case "b":
+				rec[col] = "false"
+				if c.V == "1" {
+					rec[col] = "true"
+				}
+			case "", "n":
+				rec[col] = c.V
+				if v, err := strconv.ParseFloat(c.V, 64); err == nil {
+					rec[col] = strconv.FormatFloat(v, 'f', -1, 64)
+				}
+			default:
+				rec[col] = c.V
+			}
+		}
+		if len(rec) == 0 {
+			continue
+		}
+		r.recs = append(r.recs, rec)
+	}
+	padRecords(r.recs)
+	return r, nil
+}
+
+// xlsxColumn returns the column index (starting at 0) of a cell reference
+// (e.g. "AB12").
+func xlsxColumn(ref string) int {
+	col := 0
+	for _, c := range ref {
+		if c < 'A' || c > 'Z' {
+			break
+		}
+		col = col*26 + int(c-'A') + 1
+	}
+	return col - 1
+}
+
+// xlsxColumnName returns the name of a column from its index (starting at
+// 0).
+func xlsxColumnName(col int) string {
+	var name []byte
+	for col++; col > 0; col = (col - 1) / 26 {
+		name = append([]byte{byte('A' + (col-1)%26)}, name...)
+	}
+	return string(name)
+}
+
+// xlsxWriter writes a table as an XLSX workbook. As the workbook is a zip
+// file, the table is written when Flush is called.
+type xlsxWriter struct {
+	out   io.Writer
+	head  bool // the first record is the header
+	sheet string
+	recs  [][]string
+}
+
+// newXLSXWriter returns a writer for XLSX workbooks. The parameter is the
+// name of the sheet.
+func newXLSXWriter(out io.Writer, param string) recordWriter {
+	if len(param) == 0 {
+		param = "Sheet1"
+	}
+	return &xlsxWriter{out: out, head: !noHead, sheet: param}
+}
+
+func (w *xlsxWriter) Write(record []string) error {
+	w.recs = append(w.recs, append([]string{}, record...))
+	return nil
+}
+
+// xlsxFiles are the fixed files of a workbook with a single sheet.
+var xlsxFiles = []struct {
+	name, content string
+}{
+	{"[Content_Types].xml", `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>
+</Types>`},
+	{"_rels/.rels", `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>`},
+	{"xl/_rels/workbook.xml.rels", `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+</Relationships>`},
+}
+
+func (w *xlsxWriter) Flush() error {
+	z := zip.NewWriter(w.out)
+	for _, f := range xlsxFiles {
+		fw, err := z.Create(f.name)
+		if err != nil {
+			return err
+		}
+		io.WriteString(fw, xml.Header+f.content)
+	}
+
+	fw, err := z.Create("xl/workbook.xml")
+	if err != nil {
+		return err
+	}
+	io.WriteString(fw, xml.Header)
+	io.WriteString(fw, `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="`+xlsxRelNS+`"><sheets><sheet name="`)
+	xml.EscapeText(fw, []byte(w.sheet))
+	io.WriteString(fw, `" sheetId="1" r:id="rId1"/></sheets></workbook>`)
+
+	fw, err = z.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	var b bytes.Buffer
+	b.WriteString(xml.Header)
+	b.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	for i, rec := range w.recs {
+		fmt.Fprintf(&b, `<row r="%d">`, i+1)
+		for j, f := range rec {
+			if len(f) == 0 {
+				continue
+			}
+			ref := xlsxColumnName(j) + strconv.Itoa(i+1)
+			if v, ok := getFieldValue(f).(float64); ok && (i > 0 || !w.head) && !math.IsInf(v, 0) && !math.IsNaN(v) {
+				fmt.Fprintf(&b, `<c r="%s"><v>%s</v></c>`, ref, strconv.FormatFloat(v, 'g', -1, 64))
+				continue
+			}
+			fmt.Fprintf(&b, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">`, ref)
+			xml.EscapeText(&b, []byte(f))
+			b.WriteString(`</t></is></c>`)
+		}
+		b.WriteString("</row>")
+		if _, err := fw.Write(b.Bytes()); err != nil {
+			return err
+		}
+		b.Reset()
+	}
+	b.WriteString(`</sheetData></worksheet>`)
+	if _, err := fw.Write(b.Bytes()); err != nil {
+		return err
+	}
+	return z.Close()
+}
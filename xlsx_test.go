@@ -0,0 +1,76 @@
+// Copyright (c) 2016, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestXLSX(t *testing.T) {
+	rs := [][]string{
+		[]string{"Item", "Cost", "Description"},
+		[]string{"1", "50.5", "rubber <gloves>"},
+		[]string{"2", "", "test tubes"},
+	}
+	var b bytes.Buffer
+	w := &xlsxWriter{out: &b, head: true, sheet: "Data"}
+	for _, r := range rs {
+		w.Write(r)
+	}
+	if err := w.Flush(); err != nil {
+		t.Errorf("XLSX: unexpected error: %v", err)
+	}
+	if !bytes.Contains(b.Bytes(), []byte("PK")) {
+		t.Errorf("XLSX: output is not a zip file")
+	}
+
+	for _, p := range []string{"", "Data", "1"} {
+		r, err := newXLSXReader(bytes.NewReader(b.Bytes()), p)
+		if err != nil {
+			t.Errorf("XLSX: unexpected error: %v", err)
+			continue
+		}
+		for i := 0; ; i++ {
+			row, err := r.Read()
+			if err != nil {
+				if err == io.EOF {
+					if i != len(rs) {
+						t.Errorf("XLSX: expecting %d records, found %d", len(rs), i)
+					}
+					break
+				}
+				t.Errorf("XLSX: unexpected error: %v", err)
+				break
+			}
+			if len(row) != len(rs[i]) {
+				t.Errorf("XLSX: expecting %d fields, found %d (row %d)", len(rs[i]), len(row), i)
+				continue
+			}
+			for j, v := range rs[i] {
+				if row[j] != v {
+					t.Errorf("XLSX: expecting %q in row %d col %d, found %q", v, i, j, row[j])
+				}
+			}
+		}
+	}
+
+	if _, err := newXLSXReader(bytes.NewReader(b.Bytes()), "2"); err == nil {
+		t.Errorf("XLSX: expecting error on an undefined sheet")
+	}
+}
+
+func TestXLSXColumn(t *testing.T) {
+	for i, n := range []string{"A", "Z", "AA", "AZ", "BA"} {
+		col := []int{0, 25, 26, 51, 52}[i]
+		if c := xlsxColumn(n + "12"); c != col {
+			t.Errorf("XLSX: column %s: expecting %d, found %d", n, col, c)
+		}
+		if s := xlsxColumnName(col); s != n {
+			t.Errorf("XLSX: column %d: expecting %s, found %s", col, n, s)
+		}
+	}
+}